@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -53,3 +55,88 @@ func writeLocalFile(path string, data []byte) error {
 	// #nosec G703 -- path is resolved to an absolute, cleaned path before writing.
 	return os.WriteFile(clean, data, 0o600)
 }
+
+// generatedMarker starts every module fetch-gen writes. writeLocalDir only replaces a non-empty
+// directory whose index.ts starts with it, so pointing --output-dir at a source directory cannot wipe it.
+const generatedMarker = "// Auto-generated by fetch-gen"
+
+// writeLocalDir replaces the directory at path with files, keyed by slash-separated relative path. The
+// files are written to a temporary sibling directory and swapped in by renaming, so a failed run leaves
+// the previous output in place and files of a previous run that are no longer generated are removed.
+func writeLocalDir(path string, files map[string][]byte) error {
+	if path == "" {
+		return errors.New("path is empty")
+	}
+	if strings.Contains(path, "\x00") {
+		return errors.New("invalid path")
+	}
+	if !filepath.IsAbs(path) {
+		return errors.New("path must be absolute")
+	}
+	clean := filepath.Clean(path)
+	if clean != path {
+		return errors.New("invalid path")
+	}
+	parent := filepath.Dir(clean)
+	if parent == clean {
+		return errors.New("path must name a directory")
+	}
+	if err := os.MkdirAll(parent, 0o750); err != nil {
+		return err
+	}
+	if err := checkGeneratedDir(clean); err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(clean)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0o750); err != nil {
+		return err
+	}
+	for name, data := range files {
+		local := filepath.FromSlash(name)
+		if !filepath.IsLocal(local) {
+			return fmt.Errorf("invalid output file name %q", name)
+		}
+		target := filepath.Join(staging, local)
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return err
+		}
+		// #nosec G703 -- target is a local path inside the staging directory.
+		if err := os.WriteFile(target, data, 0o600); err != nil {
+			return err
+		}
+	}
+
+	previous := staging + ".old"
+	if err := os.Rename(clean, previous); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(staging, clean); err != nil {
+		_ = os.Rename(previous, clean)
+		return err
+	}
+	return os.RemoveAll(previous)
+}
+
+// checkGeneratedDir fails unless dir is missing, empty, or holds a previous fetch-gen output.
+func checkGeneratedDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.ts"))
+	if err != nil || !bytes.HasPrefix(index, []byte(generatedMarker)) {
+		return fmt.Errorf("refusing to replace %s: it is not empty and was not generated by fetch-gen", dir)
+	}
+	return nil
+}
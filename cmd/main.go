@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,27 +20,49 @@ func main() {
 	os.Exit(0)
 }
 
+const usage = "Usage: fetch-gen --input openapi.yaml (--output ./src/api.ts | --output-dir ./src/api) [--instance ./path/to/client] [--media-types application/json,*/*+json] [--accept-overloads] [--group-by-tag] [--include-tags a,b] [--exclude-tags a,b] [--include-operations id,id] [--include-paths /glob/**] [--exclude-paths /glob/**] [--exclude-deprecated] [--include-internal] [--prune-schemas] [--format-types date-time=IsoDateString:./types]"
+
 func run() error {
-	if len(os.Args) < 5 || os.Args[1] != "--input" || os.Args[3] != "--output" {
-		fmt.Println("Usage: fetch-gen --input openapi.yaml --output ./src/api.ts [--instance ./path/to/client]")
+	flags := flag.NewFlagSet("fetch-gen", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	input := flags.String("input", "", "path to the OpenAPI document")
+	output := flags.String("output", "", "TypeScript file to write")
+	outputDir := flags.String("output-dir", "", "directory to write a module per schema and tag to")
+	instance := flags.String("instance", "@fgrzl/fetch", "module the generated code imports FetchClient from")
+	mediaTypes := flags.String("media-types", "", "comma-separated media type preference, most preferred first")
+	acceptOverloads := flags.Bool("accept-overloads", false, "generate an accept option and overloads for alternative response media types")
+	groupByTag := flags.Bool("group-by-tag", false, "nest adapter methods under the first tag of each operation")
+	includeTags := flags.String("include-tags", "", "comma-separated tags; keep only operations with one of them")
+	excludeTags := flags.String("exclude-tags", "", "comma-separated tags; drop operations with any of them")
+	includeOperations := flags.String("include-operations", "", "comma-separated operationIds to keep")
+	includePaths := flags.String("include-paths", "", "comma-separated path globs; keep only operations matching one")
+	excludePaths := flags.String("exclude-paths", "", "comma-separated path globs; drop operations matching any")
+	excludeDeprecated := flags.Bool("exclude-deprecated", false, "drop deprecated operations and optional deprecated query parameters and properties")
+	includeInternal := flags.Bool("include-internal", false, "generate operations, schemas and properties marked x-internal")
+	formatTypes := flags.String("format-types", "", "comma-separated format=Type or format=Type:module entries mapping schema formats to TypeScript types")
+	pruneSchemas := flags.Bool("prune-schemas", false, "emit only the component schemas the generated operations reference")
+	if err := flags.Parse(os.Args[1:]); err != nil || *input == "" || (*output == "") == (*outputDir == "") || flags.NArg() > 0 {
+		fmt.Println(usage)
 		return fmt.Errorf("invalid arguments")
 	}
 
-	inputPath := os.Args[2]
-	inputPath, err := filepath.Abs(inputPath)
+	formats, err := parseFormatTypes(*formatTypes)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for input: %w", err)
+		return err
 	}
 
-	outputPath := os.Args[4]
-	outputPath, err = filepath.Abs(outputPath)
+	inputPath, err := filepath.Abs(*input)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for output: %w", err)
+		return fmt.Errorf("failed to get absolute path for input: %w", err)
 	}
 
-	instance := "@fgrzl/fetch"
-	if len(os.Args) >= 7 && os.Args[5] == "--instance" {
-		instance = strings.TrimSuffix(os.Args[6], ".ts")
+	target := *output
+	if *outputDir != "" {
+		target = *outputDir
+	}
+	outputPath, err := filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %w", err)
 	}
 
 	data, err := readLocalFile(inputPath)
@@ -51,7 +75,42 @@ func run() error {
 		return err
 	}
 
-	out, err := generator.Generate(api, instance)
+	opts := generator.Options{
+		Instance:          strings.TrimSuffix(*instance, ".ts"),
+		MediaTypes:        splitList(*mediaTypes),
+		AcceptOverloads:   *acceptOverloads,
+		GroupByTag:        *groupByTag,
+		IncludeTags:       splitList(*includeTags),
+		ExcludeTags:       splitList(*excludeTags),
+		IncludeOperations: splitList(*includeOperations),
+		IncludePaths:      splitList(*includePaths),
+		ExcludePaths:      splitList(*excludePaths),
+		ExcludeDeprecated: *excludeDeprecated,
+		IncludeInternal:   *includeInternal,
+		PruneSchemas:      *pruneSchemas,
+		FormatTypes:       formats,
+		Warn: func(message string) {
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", message)
+		},
+	}
+
+	if *outputDir != "" {
+		files, err := generator.GenerateFiles(api, opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate output: %w", err)
+		}
+		contents := make(map[string][]byte, len(files))
+		for _, file := range files {
+			contents[file.Path] = file.Content
+		}
+		if err := writeLocalDir(outputPath, contents); err != nil {
+			return fmt.Errorf("failed to write output directory: %w", err)
+		}
+		fmt.Printf("✅ Generated fetch client: %s (%d files)\n", outputPath, len(files))
+		return nil
+	}
+
+	out, err := generator.Generate(api, opts)
 	if err != nil {
 		return fmt.Errorf("failed to generate output: %w", err)
 	}
@@ -63,3 +122,33 @@ func run() error {
 	fmt.Printf("✅ Generated fetch client: %s\n", outputPath)
 	return nil
 }
+
+// parseFormatTypes parses comma-separated format=Type entries; format=Type:module imports Type from module.
+func parseFormatTypes(value string) (map[string]generator.TypeOverride, error) {
+	entries := splitList(value)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	formats := make(map[string]generator.TypeOverride, len(entries))
+	for _, entry := range entries {
+		format, target, ok := strings.Cut(entry, "=")
+		typeName, module, _ := strings.Cut(target, ":")
+		format, typeName, module = strings.TrimSpace(format), strings.TrimSpace(typeName), strings.TrimSpace(module)
+		if !ok || format == "" || typeName == "" {
+			return nil, fmt.Errorf("invalid --format-types entry %q: expected format=Type or format=Type:module", entry)
+		}
+		formats[format] = generator.TypeOverride{Type: typeName, Module: module}
+	}
+	return formats, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -44,3 +44,277 @@ func TestShouldWriteOutputFileGivenValidOpenAPISpecWhenRunningThenCreateOutput(t
 	require.NoError(t, err)
 	assert.NotEmpty(t, content)
 }
+
+func TestShouldApplyContentNegotiationFlagsGivenMediaTypesAndAcceptOverloadsWhenRunningThenGenerateOverloads(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "openapi-content-negotiation.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--media-types", "text/*, */*",
+		"--accept-overloads",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `accept: "application/vnd.api+json"`)
+	assert.Contains(t, string(content), "Promise<FetchResponse<string> & { ok: true } | ApiErrorResponse<404, Problem>>")
+}
+
+func TestShouldReturnErrorGivenUnknownFlagWhenRunningThenFail(t *testing.T) {
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{"fetch-gen", "--input", "in.yaml", "--output", "out.ts", "--bogus"}
+
+	err := run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid arguments")
+}
+
+func TestShouldGroupMethodsGivenGroupByTagFlagWhenRunningThenNestSubAdapters(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "auth-api.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--group-by-tag",
+		"--input", inputPath,
+		"--output", outputPath,
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "  emailVerification: {\n")
+	assert.Contains(t, string(content), "    sso: {\n")
+}
+
+func TestShouldWriteModulesGivenOutputDirWhenRunningThenReplaceDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "auth-api.yaml")
+	outputDir := filepath.Join(tmpDir, "api")
+	require.NoError(t, os.MkdirAll(filepath.Join(outputDir, "adapters"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "index.ts"), []byte("// Auto-generated by fetch-gen\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "adapters", "removed.ts"), []byte("// stale\n"), 0o600))
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output-dir", outputDir,
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	index, err := os.ReadFile(filepath.Join(outputDir, "index.ts"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "export function createAdapter(")
+	assert.FileExists(t, filepath.Join(outputDir, "adapters", "sso.ts"))
+	assert.FileExists(t, filepath.Join(outputDir, "models", "UserIdentity.ts"))
+	assert.NoFileExists(t, filepath.Join(outputDir, "adapters", "removed.ts"))
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestShouldRefuseToReplaceDirectoryGivenOutputDirNotGeneratedWhenRunningThenFail(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "auth-api.yaml")
+	sourcePath := filepath.Join(tmpDir, "main.ts")
+	require.NoError(t, os.WriteFile(sourcePath, []byte("console.log('hi');\n"), 0o600))
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output-dir", tmpDir,
+	}
+
+	err := run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to replace")
+	assert.FileExists(t, sourcePath)
+}
+
+func TestShouldReturnErrorGivenOutputAndOutputDirWhenRunningThenFail(t *testing.T) {
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{"fetch-gen", "--input", "in.yaml", "--output", "out.ts", "--output-dir", "out"}
+
+	err := run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid arguments")
+}
+
+func TestShouldFilterOperationsGivenTagAndPathFlagsWhenRunningThenGenerateSubset(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "auth-api.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--include-tags", "Email Verification, SSO",
+		"--exclude-paths", "/auth/verify/**",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "export function getVerificationStatus(")
+	assert.Contains(t, string(content), "export function ssoLogin(")
+	assert.NotContains(t, string(content), "export function verifyEmail(")
+	assert.NotContains(t, string(content), "JWKSResponse")
+}
+
+func TestShouldDropUnreferencedSchemasGivenPruneSchemasFlagWhenRunningThenEmitReachableTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "openapi-nullable-enum.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--prune-schemas",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "export interface Thing {")
+	assert.NotContains(t, string(content), "MaybeString")
+}
+
+func TestShouldOmitDeprecatedOperationsGivenExcludeDeprecatedFlagWhenRunningThenGenerateCurrentOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "openapi-deprecated.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--exclude-deprecated",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "export function listUsers(")
+	assert.NotContains(t, string(content), "getUserLegacy")
+}
+
+func TestShouldIncludeInternalOperationsGivenIncludeInternalFlagWhenRunningThenGenerateThem(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "openapi-internal.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--include-internal",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "export function purgeUsers(")
+	assert.NotContains(t, string(content), "debugDump")
+}
+
+func TestShouldMapFormatsGivenFormatTypesFlagWhenRunningThenImportTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join("..", "tests", "fixtures", "openapi-type-overrides.yaml")
+	outputPath := filepath.Join(tmpDir, "api.ts")
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{
+		"fetch-gen",
+		"--input", inputPath,
+		"--output", outputPath,
+		"--format-types", "date-time=IsoDateString:./branded, int64=bigint",
+	}
+
+	err := run()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "import type { IsoDateString } from './branded';")
+	assert.Contains(t, string(content), "issuedAt: IsoDateString;")
+	assert.Contains(t, string(content), "id: bigint;")
+}
+
+func TestShouldReturnErrorGivenMalformedFormatTypesWhenRunningThenFail(t *testing.T) {
+	originalArgs := os.Args
+	t.Cleanup(func() {
+		os.Args = originalArgs
+	})
+
+	os.Args = []string{"fetch-gen", "--input", "in.yaml", "--output", "out.ts", "--format-types", "date-time"}
+
+	err := run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid --format-types entry "date-time"`)
+}
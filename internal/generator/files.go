@@ -0,0 +1,377 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	apitypes "github.com/fgrzl/fetch-gen/internal/types"
+)
+
+// File is one generated module. Path is relative to the output directory and uses forward slashes.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+const generatedHeader = "// Auto-generated by fetch-gen"
+
+// adapterModule is the adapter of one tag group in the multi-file output.
+type adapterModule struct {
+	Key    string
+	Name   string
+	Create string
+	Ops    []namedOperation
+}
+
+// tsSymbol is a name a module exports, so generated modules can import it where they use it. External
+// symbols come from modules outside the output directory, such as the fetch client or override types.
+type tsSymbol struct {
+	Name     string
+	Module   string
+	Type     bool
+	External bool
+}
+
+// GenerateFiles renders the API as a directory of modules: a file per schema under models/, an adapter
+// per first operation tag under adapters/ (untagged operations go to the DefaultGroup adapter), shared
+// runtime helpers in helpers.ts, and an index.ts barrel that re-exports them and defines createAdapter.
+// Each module imports only what it uses, so bundlers can drop unused adapters and models.
+func GenerateFiles(api *apitypes.OpenAPI, opts Options) ([]File, error) {
+	g, err := newGeneration(api, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := []tsSymbol{
+		{Name: "FetchClient", Module: g.instance, Type: true, External: true},
+		{Name: "FetchResponse", Module: g.instance, Type: true, External: true},
+		{Name: "buildQueryParams", Module: g.instance, External: true},
+	}
+	// Schema names that differ only in case get distinct files, which case-insensitive file systems
+	// would otherwise merge.
+	models := make([]string, 0, len(g.schemas))
+	taken := map[string]struct{}{}
+	for _, s := range g.schemas {
+		model := foldedName(s.Name, taken)
+		if model != s.Name && opts.Warn != nil {
+			opts.Warn(fmt.Sprintf("schema %q is written to models/%s.ts, since another schema's file name differs from it only in case", s.Name, model))
+		}
+		models = append(models, model)
+		symbols = append(symbols, tsSymbol{Name: s.Name, Module: "models/" + model, Type: true})
+	}
+
+	// Helpers the single-file output exports are public; the rest are exported only for the adapters.
+	public, err := g.render("helpers", g.data)
+	if err != nil {
+		return nil, err
+	}
+	data := maps.Clone(g.data)
+	data["ExportHelpers"] = true
+	helpers, err := g.render("helpers", data)
+	if err != nil {
+		return nil, err
+	}
+	symbols = append(symbols, exportedSymbols(helpers, "helpers")...)
+
+	adapters := make([]adapterModule, 0, len(g.groups))
+	for _, group := range g.groups {
+		name := apitypes.PascalCase(group.Key) + "Adapter"
+		if !isTSIdentifier(name) {
+			name = "Tag" + name
+		}
+		name = g.r.helperTypeName(name)
+		adapter := adapterModule{Key: group.Key, Name: name, Create: "create" + name, Ops: group.Ops}
+		adapters = append(adapters, adapter)
+		symbols = append(symbols,
+			tsSymbol{Name: adapter.Name, Module: "adapters/" + adapter.Key, Type: true},
+			tsSymbol{Name: adapter.Create, Module: "adapters/" + adapter.Key},
+		)
+	}
+
+	files := []File{}
+	for i, s := range g.schemas {
+		body, err := g.render("model", s)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, g.file("models/"+models[i], body, body, symbols))
+	}
+	if strings.TrimSpace(helpers) != "" {
+		files = append(files, g.file("helpers", helpers, helpers, symbols))
+	}
+	for _, adapter := range adapters {
+		data["Adapter"] = adapter
+		body, err := g.render("adapterFile", data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, g.file("adapters/"+adapter.Key, body, body, symbols))
+	}
+
+	var helperTypes, helperValues []string
+	for _, symbol := range exportedSymbols(public, "helpers") {
+		if symbol.Type {
+			helperTypes = append(helperTypes, symbol.Name)
+		} else {
+			helperValues = append(helperValues, symbol.Name)
+		}
+	}
+	data["Models"] = models
+	data["Adapters"] = adapters
+	data["HelperTypes"] = helperTypes
+	data["HelperValues"] = helperValues
+	data["GroupByTag"] = opts.GroupByTag
+	exports, err := g.render("indexExports", data)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := g.render("indexAdapter", data)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, g.file("index", strings.TrimSpace(exports)+"\n\n"+strings.TrimSpace(factory), factory, symbols))
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (g *generation) render(name string, data any) (string, error) {
+	var out bytes.Buffer
+	if err := g.tmpl.ExecuteTemplate(&out, name, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// file assembles module from body, importing the symbols of other modules that code references. The
+// override types rendered so far are importable too, so body must be rendered before file is called.
+func (g *generation) file(module string, body string, code string, symbols []tsSymbol) File {
+	used := referencedIdentifiers(code)
+	types := map[string][]string{}
+	values := map[string][]string{}
+	seen := map[string]bool{}
+	external := map[string]bool{}
+	var order []string
+	for _, imported := range g.r.typeImports() {
+		for _, name := range imported.Names {
+			symbols = append(symbols, tsSymbol{Name: name, Module: imported.Module, Type: true, External: true})
+		}
+	}
+	for _, symbol := range symbols {
+		if symbol.Module == module || !used[symbol.Name] {
+			continue
+		}
+		if !seen[symbol.Module] {
+			seen[symbol.Module] = true
+			external[symbol.Module] = symbol.External
+			order = append(order, symbol.Module)
+		}
+		if symbol.Type {
+			types[symbol.Module] = append(types[symbol.Module], symbol.Name)
+		} else {
+			values[symbol.Module] = append(values[symbol.Module], symbol.Name)
+		}
+		delete(used, symbol.Name)
+	}
+	// The fetch client module comes first, then other external modules, then generated modules by path.
+	rank := func(module string) int {
+		switch {
+		case module == g.instance:
+			return 0
+		case external[module]:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if rank(order[i]) != rank(order[j]) {
+			return rank(order[i]) < rank(order[j])
+		}
+		return order[i] < order[j]
+	})
+
+	var out strings.Builder
+	out.WriteString(generatedHeader + "\n")
+	for _, imported := range order {
+		specifier := importSpecifier(path.Dir(module), imported, external[imported])
+		if names := types[imported]; len(names) > 0 {
+			slices.Sort(names)
+			fmt.Fprintf(&out, "import type { %s } from '%s';\n", strings.Join(names, ", "), specifier)
+		}
+		if names := values[imported]; len(names) > 0 {
+			slices.Sort(names)
+			fmt.Fprintf(&out, "import { %s } from '%s';\n", strings.Join(names, ", "), specifier)
+		}
+	}
+	out.WriteString("\n" + strings.TrimSpace(body) + "\n")
+	return File{Path: module + ".ts", Content: []byte(out.String())}
+}
+
+// importSpecifier is the path a module in dir imports module from. A relative external module, such
+// as the fetch client, is given relative to the output directory, so it gains a ../ in subdirectories.
+func importSpecifier(dir string, module string, external bool) string {
+	if external {
+		if dir == "." || !strings.HasPrefix(module, ".") {
+			return module
+		}
+		return path.Join("..", module)
+	}
+	switch {
+	case dir == ".":
+		return "./" + module
+	case path.Dir(module) == dir:
+		return "./" + path.Base(module)
+	default:
+		return "../" + module
+	}
+}
+
+var exportPattern = regexp.MustCompile(`(?m)^export (type|interface|class|const|function|async function\*?)\s+([A-Za-z_$][\w$]*)`)
+
+// exportedSymbols lists the top-level declarations code exports.
+func exportedSymbols(code string, module string) []tsSymbol {
+	var symbols []tsSymbol
+	for _, match := range exportPattern.FindAllStringSubmatch(code, -1) {
+		symbols = append(symbols, tsSymbol{Name: match[2], Module: module, Type: match[1] == "type" || match[1] == "interface"})
+	}
+	return symbols
+}
+
+// referencedIdentifiers returns the identifiers code may refer to another module by: comments, string
+// and template literals, regular expression literals, member accesses (x.name, but not spreads) and
+// property keys (name: or name?:) are skipped. A slash starts a regular expression where an operand is
+// expected, that is after an operator, an opening bracket or return. String literals end at a line
+// break, so a misread quote cannot hide more than the rest of its line.
+func referencedIdentifiers(code string) map[string]bool {
+	isIdentStart := func(c byte) bool { return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+	isIdentPart := func(c byte) bool { return isIdentStart(c) || c >= '0' && c <= '9' }
+	used := map[string]bool{}
+	// operand reports whether the last token leaves the parser expecting an operand.
+	operand := true
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case strings.HasPrefix(code[i:], "//"):
+			if end := strings.IndexByte(code[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		case strings.HasPrefix(code[i:], "/*"):
+			if end := strings.Index(code[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(code)
+			}
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(code) && code[i] != c && (c == '`' || code[i] != '\n'); i++ {
+				if code[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			operand = false
+		case c == '/' && operand:
+			inClass := false
+			for i++; i < len(code) && code[i] != '\n' && (inClass || code[i] != '/'); i++ {
+				switch code[i] {
+				case '\\':
+					i++
+				case '[':
+					inClass = true
+				case ']':
+					inClass = false
+				}
+			}
+			for i++; i < len(code) && isIdentPart(code[i]); {
+				i++
+			}
+			operand = false
+		case isIdentStart(c):
+			start := i
+			for i < len(code) && isIdentPart(code[i]) {
+				i++
+			}
+			next := strings.TrimLeft(code[i:], " \t?")
+			member := start > 0 && code[start-1] == '.' && !strings.HasSuffix(code[:start], "...")
+			if !member && !strings.HasPrefix(next, ":") {
+				used[code[start:i]] = true
+			}
+			operand = code[start:i] == "return"
+		case c >= '0' && c <= '9':
+			for i < len(code) && isIdentPart(code[i]) {
+				i++
+			}
+			operand = false
+		default:
+			if !strings.ContainsRune(" \t\r\n", rune(c)) {
+				operand = !strings.ContainsRune(")]}", rune(c))
+			}
+			i++
+		}
+	}
+	return used
+}
+
+const filesTemplate = `
+{{- define "adapterFile"}}
+{{- template "functions" .Adapter.Ops}}
+
+/**
+ * Typed methods for the operations {{if eq .Adapter.Key "default"}}without a tag{{else}}in the {{.Adapter.Key}} group{{end}}.
+ */
+export interface {{.Adapter.Name}} {
+{{- template "signatures" .Adapter.Ops}}
+}
+
+/**
+ * Creates the adapter for the operations {{if eq .Adapter.Key "default"}}without a tag{{else}}in the {{.Adapter.Key}} group{{end}}.
+ *
+ * @param client - The FetchClient instance to use for HTTP requests
+{{- if .AdapterConfigName}}
+ * @param config - Adapter configuration
+{{- end}}
+ * @returns An object with typed methods for each operation in the group
+ */
+export function {{.Adapter.Create}}(client: FetchClient{{if .AdapterConfigName}}, config: {{.AdapterConfigName}} = {}{{end}}): {{.Adapter.Name}} {
+  return {
+{{- template "methods" .Adapter.Ops}}
+  };
+}
+{{- end}}
+{{- define "indexExports"}}
+{{- range .Models}}
+export * from './models/{{.}}';
+{{- end}}
+{{- with .HelperTypes}}
+export type { {{join . ", "}} } from './helpers';
+{{- end}}
+{{- with .HelperValues}}
+export { {{join . ", "}} } from './helpers';
+{{- end}}
+{{- range .Adapters}}
+export * from './adapters/{{.Key}}';
+{{- end}}
+{{- end}}
+{{- define "indexAdapter"}}
+{{- template "createAdapterDoc" .}}
+export function createAdapter(client: FetchClient{{if .AdapterConfigName}}, config: {{.AdapterConfigName}} = {}{{end}}): {{if .GroupByTag}}{
+{{- range .Adapters}}
+  {{tsPropertyKey .Key}}: {{.Name}};
+{{- end}}
+}{{else}}{{range $i, $adapter := .Adapters}}{{if $i}} & {{end}}{{$adapter.Name}}{{else}}{}{{end}}{{end}} {
+  return {
+{{- range .Adapters}}
+    {{if $.GroupByTag}}{{tsPropertyKey .Key}}: {{else}}...{{end}}{{.Create}}(client{{if $.AdapterConfigName}}, config{{end}}),
+{{- end}}
+  };
+}
+{{- end}}
+`
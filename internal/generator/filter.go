@@ -0,0 +1,233 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	apitypes "github.com/fgrzl/fetch-gen/internal/types"
+)
+
+// Extensions that keep an operation, schema or property out of the generated client:
+// x-fetch-gen-ignore always, x-internal unless Options.IncludeInternal is set.
+const (
+	ignoreExtension   = "x-fetch-gen-ignore"
+	internalExtension = "x-internal"
+)
+
+// hidden reports whether the extensions of an operation, schema or property keep it out of the client.
+func hidden(extensions apitypes.Extensions, includeInternal bool) bool {
+	return extensions.Bool(ignoreExtension) || !includeInternal && extensions.Bool(internalExtension)
+}
+
+// selectsOperations reports whether opts keeps only some operations by tag, operationId or path.
+func selectsOperations(opts Options) bool {
+	return len(opts.IncludeTags)+len(opts.ExcludeTags)+len(opts.IncludeOperations)+len(opts.IncludePaths)+len(opts.ExcludePaths) > 0
+}
+
+// filterOperations returns api with only the operations opts selects. An operation is kept when it
+// passes every filter and is not hidden by an extension.
+func filterOperations(api *apitypes.OpenAPI, opts Options) (*apitypes.OpenAPI, error) {
+	for _, pattern := range slices.Concat(opts.IncludePaths, opts.ExcludePaths) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+
+	filtered := *api
+	filtered.Paths = map[string]map[string]*apitypes.Operation{}
+	seenTags := map[string]struct{}{}
+	seenOperations := map[string]struct{}{}
+	for _, p := range apitypes.SortedKeys(api.Paths) {
+		for method, op := range api.Paths[p] {
+			if op != nil {
+				for _, tag := range op.Tags {
+					seenTags[tag] = struct{}{}
+				}
+				seenOperations[op.OperationID] = struct{}{}
+				if !keepOperation(opts, p, op) {
+					continue
+				}
+			}
+			if filtered.Paths[p] == nil {
+				filtered.Paths[p] = map[string]*apitypes.Operation{}
+			}
+			filtered.Paths[p][method] = op
+		}
+	}
+
+	if opts.Warn != nil {
+		for _, tag := range opts.IncludeTags {
+			if _, ok := seenTags[tag]; !ok {
+				opts.Warn(fmt.Sprintf("no operation is tagged %q", tag))
+			}
+		}
+		for _, id := range opts.IncludeOperations {
+			if _, ok := seenOperations[id]; !ok {
+				opts.Warn(fmt.Sprintf("no operation has operationId %q", id))
+			}
+		}
+	}
+
+	return &filtered, nil
+}
+
+// pruneSchemas returns api, the operations of document that opts keeps, without the component
+// schemas hidden by an extension or reached only through the operations and properties left out, so
+// they stay out of the client too. Schemas no operation of document references are kept, unless
+// Options.PruneSchemas is set or opts selects a subset of the operations. A hidden schema a generated
+// operation reaches is kept, with a warning.
+func pruneSchemas(document, api *apitypes.OpenAPI, opts Options) *apitypes.OpenAPI {
+	keepUnreferenced := !opts.PruneSchemas && !selectsOperations(opts)
+	reached := reachableSchemas(api, func(s, sub *apitypes.Schema) bool { return hiddenProperty(s, sub, opts.IncludeInternal) })
+	referenced := reachableSchemas(document, func(s, sub *apitypes.Schema) bool { return false })
+	kept := map[string]*apitypes.Schema{}
+	for _, name := range apitypes.SortedKeys(api.Components.Schemas) {
+		s := api.Components.Schemas[name]
+		_, ok := reached[name]
+		_, used := referenced[name]
+		switch {
+		case ok && s != nil && hidden(s.Extensions, opts.IncludeInternal):
+			if opts.Warn != nil {
+				opts.Warn(fmt.Sprintf("schema %q is hidden by an extension but a generated operation references it", name))
+			}
+			kept[name] = s
+		case ok, keepUnreferenced && !used && (s == nil || !hidden(s.Extensions, opts.IncludeInternal)):
+			kept[name] = s
+		}
+	}
+	if len(kept) == len(api.Components.Schemas) {
+		return api
+	}
+	pruned := *api
+	pruned.Components.Schemas = kept
+	return &pruned
+}
+
+// hiddenProperty reports whether sub is a property of s that an extension keeps out of the client.
+func hiddenProperty(s *apitypes.Schema, sub *apitypes.Schema, includeInternal bool) bool {
+	if sub == nil || !hidden(sub.Extensions, includeInternal) {
+		return false
+	}
+	for _, prop := range s.Properties {
+		if prop == sub {
+			return true
+		}
+	}
+	return false
+}
+
+func keepOperation(opts Options, p string, op *apitypes.Operation) bool {
+	hasTag := func(tags []string) bool {
+		return slices.ContainsFunc(op.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	}
+	matchesPath := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool { return matchPathGlob(pattern, p) })
+	}
+	switch {
+	case len(opts.IncludeTags) > 0 && !hasTag(opts.IncludeTags):
+		return false
+	case len(opts.IncludeOperations) > 0 && !slices.Contains(opts.IncludeOperations, op.OperationID):
+		return false
+	case len(opts.IncludePaths) > 0 && !matchesPath(opts.IncludePaths):
+		return false
+	case hasTag(opts.ExcludeTags), matchesPath(opts.ExcludePaths), opts.ExcludeDeprecated && op.Deprecated:
+		return false
+	case hidden(op.Extensions, opts.IncludeInternal):
+		return false
+	}
+	return true
+}
+
+// matchPathGlob reports whether an OpenAPI path such as /users/{id} matches pattern. Within a segment,
+// * and ? match as in path.Match; a ** segment matches any number of segments.
+func matchPathGlob(pattern string, p string) bool {
+	return matchPathSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(strings.Trim(p, "/"), "/"))
+}
+
+func matchPathSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchPathSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchPathSegments(pattern[1:], segments[1:])
+}
+
+// reachableSchemas returns the component schemas the operations of api refer to, directly or through
+// other components, parameters and headers. Subschemas skip reports true for are not followed.
+func reachableSchemas(api *apitypes.OpenAPI, skip func(s, sub *apitypes.Schema) bool) map[string]*apitypes.Schema {
+	reached := map[string]*apitypes.Schema{}
+	visited := map[*apitypes.Schema]struct{}{}
+	var visit func(s *apitypes.Schema)
+	visit = func(s *apitypes.Schema) {
+		if s == nil {
+			return
+		}
+		if _, ok := visited[s]; ok {
+			return
+		}
+		visited[s] = struct{}{}
+		if s.Ref != "" {
+			name := extractRefName(s.Ref)
+			if target, ok := api.Components.Schemas[name]; ok {
+				reached[name] = target
+				visit(target)
+			}
+		}
+		for _, sub := range s.Subschemas() {
+			if !skip(s, sub) {
+				visit(sub)
+			}
+		}
+	}
+
+	for _, methods := range api.Paths {
+		for _, op := range methods {
+			if op == nil {
+				continue
+			}
+			for _, p := range op.Parameters {
+				if resolved, err := resolveParameter(api, p, map[string]struct{}{}); err == nil {
+					visit(resolved.Schema)
+				}
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if media != nil {
+						visit(media.Schema)
+						visit(media.ItemSchema)
+						visit(media.StreamItem)
+					}
+				}
+			}
+			for _, resp := range op.Responses {
+				if resp == nil {
+					continue
+				}
+				for _, media := range resp.Content {
+					visit(media.Schema)
+					visit(media.ItemSchema)
+					visit(media.StreamItem)
+				}
+				for _, header := range resp.Headers {
+					if resolved, err := resolveHeader(api, header, map[string]struct{}{}); err == nil {
+						visit(resolved.Schema)
+					}
+				}
+			}
+		}
+	}
+	return reached
+}
@@ -1,28 +1,92 @@
 package generator
 
 import (
-	"bytes"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
 	"unicode"
+	"unicode/utf8"
 
 	apitypes "github.com/fgrzl/fetch-gen/internal/types"
 )
 
 type namedOperation struct {
-	ID           string
-	Method       string
-	DisplayPath  string
+	ID string
+	// Function is the name of the standalone function the adapter method delegates to; Config is the
+	// adapter configuration type it takes when it needs the selected server or the auth hook.
+	Function string
+	Config   string
+	// Group is the tag group of the method: its sub-adapter with GroupByTag, its adapter module in the
+	// multi-file output.
+	Group       string
+	Method      string
+	DisplayPath string
+	// DocPath is the path shown in doc comments, without the runtime server URL.
+	DocPath      string
 	PathParams   []apitypes.Parameter
 	QueryParams  []apitypes.Parameter
 	HasBody      bool
 	BodyRequired bool
 	RequestType  string
-	ResponseType string
-	RawBody      bool
-	Description  string
+	// RequestMediaType is the preferred media type of the request body, if any.
+	RequestMediaType string
+	ResponseType     string
+	SuccessTypes     []statusType
+	ErrorTypes       []string
+	Accepts          []acceptOverload
+	// Decoding is the responseType the client reads the body with ("" for JSON); ParseXML marks XML
+	// bodies the adapter parses into a Document.
+	Decoding string
+	ParseXML bool
+	// Stream is "sse" or "ndjson" for operations that return an AsyncIterable of ResponseType items;
+	// StreamRaw marks event streams whose data is yielded as text instead of parsed as JSON.
+	Stream    string
+	StreamRaw bool
+	// Download marks binary downloads, which resolve to a FileDownload and accept an onProgress callback.
+	Download bool
+	// Headers is the object type of the declared headers of the success response, if any; HeaderKinds
+	// is the literal readResponseHeaders parses every declared success header with.
+	Headers     string
+	HeaderKinds string
+	// Security is the literal of the operation's security requirements; Secured marks operations with
+	// at least one requirement naming a scheme, whose requests go through the auth hook.
+	Security string
+	Secured  bool
+	RawBody  bool
+	// FormBody and MultipartBody mark form-urlencoded and multipart/form-data bodies; BodyEncoding is
+	// the literal of their encoding object.
+	FormBody      bool
+	MultipartBody bool
+	BodyEncoding  string
+	Description   string
+	// Server is the literal of the templated operation- or path-level server the URL is expanded from
+	// at runtime, with the variables given to createAdapter.
+	Server     string
+	Deprecated bool
+}
+
+// statusType pairs a literal status code with the type of the body returned for it and the object
+// type of the headers that response declares.
+type statusType struct {
+	Status  string
+	Type    string
+	Headers string
+}
+
+// acceptOverload is an alternative response media type a caller can request through `options.accept`.
+type acceptOverload struct {
+	MediaType  string
+	ReturnType string
+	Decoding   string
+	ParseXML   bool
+}
+
+// securityScheme is a components.securitySchemes entry rendered into the SecuritySchemes table.
+type securityScheme struct {
+	Name    string
+	Literal string
 }
 
 type templateSchema struct {
@@ -31,16 +95,137 @@ type templateSchema struct {
 	PropKeys []string
 }
 
-func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
+// Options configures code generation.
+type Options struct {
+	// Instance is the module the generated code imports FetchClient from (default: @fgrzl/fetch).
+	Instance string
+	// Warn receives non-fatal diagnostics, such as schemas TypeScript can only approximate.
+	Warn func(message string)
+	// MediaTypes orders the media types considered for request and response bodies, most preferred
+	// first (default: DefaultMediaTypes).
+	MediaTypes []string
+	// AcceptOverloads adds an `accept` option and a typed overload per alternative response media type.
+	AcceptOverloads bool
+	// GroupByTag nests adapter methods under the camelCased first tag of each operation, such as
+	// api.users.getUser(); untagged operations go to the DefaultGroup.
+	GroupByTag bool
+	// IncludeTags keeps only operations with at least one of these tags, and IncludeOperations only the
+	// operations with these operationIds; ExcludeTags drops operations with any of these tags.
+	IncludeTags       []string
+	ExcludeTags       []string
+	IncludeOperations []string
+	// IncludePaths keeps only operations whose path matches one of these globs, and ExcludePaths drops
+	// those matching any of them. Within a segment * and ? match as in path.Match; ** matches any number
+	// of segments, so /admin/** selects every path under /admin.
+	IncludePaths []string
+	ExcludePaths []string
+	// ExcludeDeprecated drops deprecated operations, optional deprecated query parameters and optional
+	// deprecated schema properties; by default they are generated with a @deprecated tag. Required
+	// members are kept, since requests and responses cannot do without them.
+	ExcludeDeprecated bool
+	// IncludeInternal generates operations, schemas and properties marked x-internal: true, which are
+	// left out by default. Those marked x-fetch-gen-ignore: true are always left out.
+	IncludeInternal bool
+	// FormatTypes maps schema formats, such as date-time or int64, to the types string, integer and
+	// number schemas with that format generate. An x-ts-type extension on a schema takes precedence.
+	FormatTypes map[string]TypeOverride
+	// PruneSchemas emits only the component schemas the generated operations reach through $ref,
+	// properties, items, allOf and the other subschemas. By default the schemas no operation references
+	// are emitted too, unless the include and exclude filters select a subset of the operations; schemas
+	// reached only through operations or properties that are left out are never emitted.
+	PruneSchemas bool
+}
+
+// TypeOverride is a TypeScript type generated in place of the type a schema describes. When Module is
+// set, the leading identifier of Type is imported from it.
+type TypeOverride struct {
+	Type   string
+	Module string
+}
+
+// typeImport is a module the generated code imports override types from.
+type typeImport struct {
+	Module string
+	Names  []string
+}
+
+const (
+	tsTypeExtensionName   = "x-ts-type"
+	tsImportExtensionName = "x-ts-import"
+)
+
+// DefaultGroup holds the methods of untagged operations when grouping by tag or splitting files.
+const DefaultGroup = "default"
+
+// operationGroup is the sub-adapter of one tag.
+type operationGroup struct {
+	Key string
+	Ops []namedOperation
+}
+
+// generation is an API resolved for rendering: its operations, schemas and tag groups, and the
+// template data shared by the single-file and multi-file outputs.
+type generation struct {
+	r        *typeResolver
+	tmpl     *template.Template
+	data     map[string]any
+	schemas  []templateSchema
+	groups   []operationGroup
+	instance string
+}
+
+// Generate renders the API as a single TypeScript module.
+func Generate(api *apitypes.OpenAPI, opts Options) ([]byte, error) {
+	g, err := newGeneration(api, opts)
+	if err != nil {
+		return nil, err
+	}
+	// The body is rendered first so the header can import the override types it uses.
+	body, err := g.render("api", g.data)
+	if err != nil {
+		return nil, err
+	}
+	header, err := g.render("header", g.data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(header + body), nil
+}
+
+func newGeneration(api *apitypes.OpenAPI, opts Options) (*generation, error) {
 	if api == nil {
 		return nil, fmt.Errorf("openapi document is empty")
 	}
+	instance := opts.Instance
 	if strings.TrimSpace(instance) == "" {
 		instance = "@fgrzl/fetch"
 	}
+	media, err := newMediaTypePreference(opts.MediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	document := api
+	api, err = filterOperations(api, opts)
+	if err != nil {
+		return nil, err
+	}
+	api = pruneSchemas(document, api, opts)
+	if opts.Warn != nil {
+		for _, warning := range schemaWarnings(api) {
+			opts.Warn(warning)
+		}
+		for _, name := range apitypes.SortedKeys(api.Components.SecuritySchemes) {
+			if isCookieScheme(api.Components.SecuritySchemes[name]) {
+				opts.Warn(fmt.Sprintf("security scheme %q: fetch cannot set the Cookie header, so the auth hook is not asked for it; send the cookie with credentials: 'include'", name))
+			}
+		}
+	}
 
+	r := newTypeResolver(api, media, opts)
+	tagKeys := groupKeys(api, opts.Warn)
 	var ops []namedOperation
-	serverPrefix := operationServerPrefix(api)
+	var templatedServers []apitypes.Server
+	defaultServerPrefix := operationServerPrefix(api)
 	for path, methods := range api.Paths {
 		for method, op := range methods {
 			if op == nil {
@@ -49,7 +234,18 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 
 			params := []apitypes.Parameter{}
 			queryParams := []apitypes.Parameter{}
+			serverPrefix := defaultServerPrefix
+			docPrefix := strings.TrimPrefix(serverPrefix, "${serverBase}")
+			operationServer := ""
+			if servers := operationServers(api, path, op); servers != nil {
+				serverPrefix, docPrefix = plainServerURL(servers[0]), plainServerURL(servers[0])
+				if isTemplatedServer(servers[0]) {
+					serverPrefix, operationServer = "${serverBase}", serverLiteral(servers[0])
+					templatedServers = append(templatedServers, servers[0])
+				}
+			}
 			displayPath := serverPrefix + path
+			docPath := docPrefix + path
 			for _, p := range op.Parameters {
 				resolved, err := resolveParameter(api, p, map[string]struct{}{})
 				if err != nil {
@@ -59,13 +255,21 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 				case "path":
 					params = append(params, *resolved)
 					displayPath = strings.ReplaceAll(displayPath, "{"+resolved.Name+"}", fmt.Sprintf("${encodeURIComponent(String(%s))}", resolved.Name))
+					docPath = strings.ReplaceAll(docPath, "{"+resolved.Name+"}", fmt.Sprintf("${encodeURIComponent(String(%s))}", resolved.Name))
 				case "query":
+					if opts.ExcludeDeprecated && resolved.Deprecated && !resolved.Required {
+						continue
+					}
 					queryParams = append(queryParams, *resolved)
 				}
 			}
 
-			reqType := requestTypeForOperation(op)
-			resType := responseTypeForOperation(op)
+			reqType := r.requestTypeForOperation(op)
+			resType := r.responseTypeForOperation(op)
+			successTypes := r.successTypesForOperation(op)
+			if len(successTypes) > 0 {
+				resType = unionOfStatusTypes(successTypes)
+			}
 
 			if method == "delete" {
 				method = "del"
@@ -76,18 +280,79 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 				description = op.Description
 			}
 
+			errorTypes := r.errorTypesForOperation(op)
+			requestMediaType := ""
+			bodyEncoding := ""
+			if op.RequestBody != nil {
+				requestMediaType, _ = r.media.requestMediaType(op.RequestBody.Content)
+				if isFormMediaType(requestMediaType) || isMultipartMediaType(requestMediaType) {
+					bodyEncoding = encodingLiteral(op.RequestBody.Content[requestMediaType].Encoding)
+				}
+			}
+			decoding, parseXML := r.responseDecodingForOperation(op)
+			headers, headerKinds, err := r.successHeadersForOperation(api, op, successTypes)
+			if err != nil {
+				return nil, err
+			}
+			var accepts []acceptOverload
+			stream, itemType, streamRaw := r.streamForOperation(op)
+			if stream != "" {
+				resType, decoding, successTypes, errorTypes = itemType, "stream", nil, nil
+				headers, headerKinds = "", nil
+			} else if opts.AcceptOverloads {
+				accepts = r.acceptOverloadsForOperation(op, errorTypes, headers)
+			}
+			// Binary downloads are read as a stream so progress can be reported while the Blob is assembled.
+			download := stream == "" && resType == "Blob" && len(successTypes) == 0
+			if download {
+				resType, decoding = r.helperTypeName("FileDownload"), "stream"
+				r.helperTypeName("DownloadProgress")
+			}
+
+			requirements := api.Security
+			if op.Security != nil {
+				requirements = *op.Security
+			}
+			security, secured := securityRequirementsLiteral(requirements)
+			secured = secured && len(api.Components.SecuritySchemes) > 0
+
+			group := DefaultGroup
+			if len(op.Tags) > 0 {
+				group = tagKeys[op.Tags[0]]
+			}
+
 			ops = append(ops, namedOperation{
-				ID:           op.OperationID,
-				Method:       method,
-				DisplayPath:  displayPath,
-				PathParams:   params,
-				QueryParams:  queryParams,
-				HasBody:      op.RequestBody != nil,
-				BodyRequired: op.RequestBody != nil && op.RequestBody.Required,
-				RequestType:  reqType,
-				ResponseType: resType,
-				RawBody:      operationHasBinaryRequest(op),
-				Description:  description,
+				ID:               op.OperationID,
+				Group:            group,
+				Method:           method,
+				DisplayPath:      displayPath,
+				DocPath:          docPath,
+				PathParams:       params,
+				QueryParams:      queryParams,
+				HasBody:          op.RequestBody != nil,
+				BodyRequired:     op.RequestBody != nil && op.RequestBody.Required,
+				RequestType:      reqType,
+				RequestMediaType: requestMediaType,
+				ResponseType:     resType,
+				SuccessTypes:     successTypes,
+				Accepts:          accepts,
+				Decoding:         decoding,
+				ParseXML:         parseXML,
+				Stream:           stream,
+				Download:         download,
+				Headers:          headers,
+				HeaderKinds:      headerKindsLiteral(headerKinds),
+				Security:         security,
+				Secured:          secured,
+				StreamRaw:        streamRaw,
+				ErrorTypes:       errorTypes,
+				RawBody:          r.operationHasBinaryRequest(op),
+				FormBody:         isFormMediaType(requestMediaType),
+				MultipartBody:    isMultipartMediaType(requestMediaType),
+				BodyEncoding:     bodyEncoding,
+				Description:      description,
+				Server:           operationServer,
+				Deprecated:       op.Deprecated,
 			})
 		}
 	}
@@ -100,12 +365,13 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 	})
 
 	funcs := template.FuncMap{
-		"tsType": resolveType,
+		"tsType":       r.resolveType,
+		"tsDefinition": r.resolveDefinition,
 		"isAlias": func(s *apitypes.Schema) bool {
 			if s == nil {
 				return true
 			}
-			if s.Ref != "" {
+			if _, ok := tsTypeExtension(s); ok || s.Ref != "" {
 				return true
 			}
 			if len(s.Enum) > 0 {
@@ -114,8 +380,11 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 			if len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
 				return true
 			}
-			if s.Type.Has("object") {
-				if s.AdditionalProperties != nil {
+			if s.Items != nil || len(s.PrefixItems) > 0 || s.Not != nil || s.If != nil {
+				return true
+			}
+			if s.Type.Has("object") || len(s.Properties) > 0 {
+				if hasDynamicProperties(s) {
 					return true
 				}
 				return len(s.Properties) == 0
@@ -123,68 +392,56 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 			return !s.Type.IsEmpty()
 		},
 		"argList": func(op namedOperation) string {
-			args := []string{}
-			for _, p := range op.PathParams {
-				paramType := resolveType(p.Schema)
-				if !p.Required {
-					paramType += " | undefined"
-				}
-				args = append(args, fmt.Sprintf("%s: %s", p.Name, paramType))
-			}
-			if len(op.QueryParams) > 0 {
-				queryProps := []string{}
-				for _, p := range op.QueryParams {
-					paramType := resolveType(p.Schema)
-					optional := "?"
-					if p.Required {
-						optional = ""
-					}
-					queryProps = append(queryProps, fmt.Sprintf("%s%s: %s", tsPropertyKey(p.Name), optional, paramType))
-				}
-				queryType := fmt.Sprintf("{ %s }", strings.Join(queryProps, "; "))
-				if hasRequiredQueryParams(op) {
-					args = append(args, fmt.Sprintf("query: %s", queryType))
-				} else {
-					args = append(args, fmt.Sprintf("query?: %s", queryType))
-				}
-			}
-			if op.HasBody {
-				bodyType := "any"
-				if op.RequestType != "" {
-					bodyType = op.RequestType
-				}
-				if op.BodyRequired {
-					args = append(args, fmt.Sprintf("body: %s", bodyType))
-				} else {
-					args = append(args, fmt.Sprintf("body?: %s", bodyType))
+			return r.argList(op, "options?: "+r.requestOptionsType(op, ""))
+		},
+		"implArgList": func(op namedOperation) string {
+			if len(op.Accepts) > 0 {
+				mediaTypes := make([]string, 0, len(op.Accepts))
+				for _, alt := range op.Accepts {
+					mediaTypes = append(mediaTypes, tsStringLiteral(alt.MediaType))
 				}
+				return r.argList(op, "options?: "+r.requestOptionsType(op, "accept?: "+strings.Join(mediaTypes, " | ")))
 			}
-			if op.RawBody {
-				args = append(args, "headers?: HeadersInit")
+			return r.argList(op, "options?: "+r.requestOptionsType(op, ""))
+		},
+		"acceptArgList": func(op namedOperation, mediaType string) string {
+			return r.argList(op, "options: "+r.requestOptionsType(op, "accept: "+tsStringLiteral(mediaType)))
+		},
+		"methodReturnType": methodReturnType,
+		"callArgs":         callArgs,
+		"usesServerBase":   usesServerBase,
+		"implReturnType": func(op namedOperation) string {
+			if len(op.Accepts) > 0 {
+				return "Promise<any>"
 			}
-			args = append(args, "options?: { signal?: AbortSignal; timeout?: number; operationId?: string }")
-			return strings.Join(args, ", ")
+			return methodReturnType(op)
 		},
+		"acceptCall": acceptCallBlock,
 		"clientCall": func(op namedOperation, urlExpr string, optionsVar string) string {
-			if op.RawBody {
-				return fmt.Sprintf("return client.request<%s>(%s, { method: %q, headers, body }, %s);", responseTypeValue(op), urlExpr, strings.ToUpper(op.Method), optionsVar)
-			}
-			switch op.Method {
-			case "get", "del", "head":
-				return fmt.Sprintf("return client.%s(%s, undefined, %s);", op.Method, urlExpr, optionsVar)
-			case "post", "put", "patch":
-				bodyArg := "undefined"
-				if op.HasBody {
-					bodyArg = "body"
+			if op.Download {
+				call := withResponseHeaders(op, fmt.Sprintf("readDownload(%s, onProgress)", clientCallExpr(op, urlExpr, optionsVar, "")))
+				if len(op.ErrorTypes) > 0 || op.HeaderKinds != "" {
+					return fmt.Sprintf("return %s as Promise<%s>;", call, returnTypeValue(op))
 				}
-				return fmt.Sprintf("return client.%s(%s, %s, undefined, %s);", op.Method, urlExpr, bodyArg, optionsVar)
-			default:
-				bodyArg := "undefined"
-				if op.HasBody {
-					bodyArg = "body"
+				return "return " + call + ";"
+			}
+			switch op.Stream {
+			case "sse":
+				parseData := "JSON.parse"
+				if op.StreamRaw {
+					parseData = "String"
 				}
-				return fmt.Sprintf("return client.%s(%s, %s, %s);", op.Method, urlExpr, bodyArg, optionsVar)
+				return fmt.Sprintf("return readEventStream<%s>(%s, %s);", op.ResponseType, clientCallExpr(op, urlExpr, optionsVar, ""), parseData)
+			case "ndjson":
+				return fmt.Sprintf("return readNdjsonStream<%s>(%s);", op.ResponseType, clientCallExpr(op, urlExpr, optionsVar, ""))
+			}
+			if op.ParseXML {
+				return fmt.Sprintf("return %s as Promise<%s>;", withResponseHeaders(op, clientCallExpr(op, urlExpr, optionsVar, "<string>")+".then(parseXmlResponse)"), returnTypeValue(op))
 			}
+			if len(op.SuccessTypes) > 0 || len(op.ErrorTypes) > 0 || op.HeaderKinds != "" {
+				return fmt.Sprintf("return %s as Promise<%s>;", withResponseHeaders(op, clientCallExpr(op, urlExpr, optionsVar, "<"+responseTypeValue(op)+">")), returnTypeValue(op))
+			}
+			return fmt.Sprintf("return %s;", clientCallExpr(op, urlExpr, optionsVar, ""))
 		},
 		"responseType": func(op namedOperation) string {
 			if op.ResponseType != "" {
@@ -192,16 +449,21 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 			}
 			return "any"
 		},
+		"returnType": returnTypeValue,
 		"hasQueryParams": func(op namedOperation) bool {
 			return len(op.QueryParams) > 0
 		},
 		"upper": strings.ToUpper,
+		"join":  strings.Join,
 		"add": func(a, b int) int {
 			return a + b
 		},
 		"len": func(slice []namedOperation) int {
 			return len(slice)
 		},
+		"lenGroups": func(slice []operationGroup) int {
+			return len(slice)
+		},
 		"contains": func(slice []string, item string) bool {
 			for _, s := range slice {
 				if s == item {
@@ -212,41 +474,115 @@ func Generate(api *apitypes.OpenAPI, instance string) ([]byte, error) {
 		},
 		"tsPropertyKey":   tsPropertyKey,
 		"tsStringLiteral": tsStringLiteral,
+		"typeImports":     r.typeImports,
 	}
 
-	sortedSchemaNames := []string{}
-	for name := range api.Components.Schemas {
-		sortedSchemaNames = append(sortedSchemaNames, name)
-	}
-	sort.Strings(sortedSchemaNames)
-
 	sortedSchemas := []templateSchema{}
-	for _, name := range sortedSchemaNames {
+	for _, name := range apitypes.SortedKeys(api.Components.Schemas) {
 		s := api.Components.Schemas[name]
-		propKeys := []string{}
-		if s != nil && s.Properties != nil {
-			for pk := range s.Properties {
-				propKeys = append(propKeys, pk)
-			}
-			sort.Strings(propKeys)
+		sortedSchemas = append(sortedSchemas, templateSchema{Name: r.schemaTypeName(name), Schema: s, PropKeys: r.propertyKeys(s)})
+	}
+	sortedSchemas = append(sortedSchemas, r.hoisted...)
+	sort.SliceStable(sortedSchemas, func(i, j int) bool { return sortedSchemas[i].Name < sortedSchemas[j].Name })
+
+	if anyOperation(ops, usesCheckedDecoding) {
+		r.helperTypeName("ResponseTypeError")
+	}
+
+	servers := []string{}
+	if hasServerSelection(api.Servers) {
+		for _, server := range api.Servers {
+			servers = append(servers, serverLiteral(server))
+		}
+	}
+	serverVariables := ""
+	if len(servers) > 0 || len(templatedServers) > 0 {
+		serverVariables = serverVariablesType(slices.Concat(api.Servers, templatedServers))
+		r.helperTypeName("Server")
+		r.helperTypeName("ServerVariables")
+	}
+
+	securitySchemes := []securityScheme{}
+	cookieSchemes := false
+	adapterConfigName := ""
+	if serverVariables != "" {
+		adapterConfigName = r.helperTypeName("AdapterConfig")
+	}
+	if len(api.Components.SecuritySchemes) > 0 {
+		for _, name := range apitypes.SortedKeys(api.Components.SecuritySchemes) {
+			securitySchemes = append(securitySchemes, securityScheme{Name: name, Literal: securitySchemeLiteral(api.Components.SecuritySchemes[name])})
+			cookieSchemes = cookieSchemes || isCookieScheme(api.Components.SecuritySchemes[name])
+		}
+		adapterConfigName = r.helperTypeName("AdapterConfig")
+		r.helperTypeName("SecurityScheme")
+		r.helperTypeName("SecurityRequirement")
+		r.helperTypeName("AuthRequest")
+		r.helperTypeName("AuthCredential")
+	}
+
+	nameOperationFunctions(ops, r.taken)
+	for i := range ops {
+		if adapterConfigName != "" && (ops[i].Secured || usesServerBase(ops[i])) {
+			ops[i].Config = adapterConfigName
 		}
-		sortedSchemas = append(sortedSchemas, templateSchema{Name: name, Schema: s, PropKeys: propKeys})
 	}
 
-	tmpl := template.Must(template.New("api").Funcs(funcs).Parse(apiTemplate))
-	var out bytes.Buffer
-	if err := tmpl.Execute(&out, map[string]any{
-		"SortedSchemas": sortedSchemas,
-		"Ops":           ops,
-		"Instance":      instance,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+	groups := []operationGroup{}
+	byKey := map[string][]namedOperation{}
+	for _, op := range ops {
+		byKey[op.Group] = append(byKey[op.Group], op)
+	}
+	for _, key := range apitypes.SortedKeys(byKey) {
+		groups = append(groups, operationGroup{Key: key, Ops: byKey[key]})
+	}
+	templateGroups := []operationGroup{}
+	if opts.GroupByTag {
+		templateGroups = groups
+	}
+
+	tmpl := template.Must(template.Must(template.New("api").Funcs(funcs).Parse(apiTemplate)).Parse(filesTemplate))
+	data := map[string]any{
+		"SortedSchemas":           sortedSchemas,
+		"Ops":                     ops,
+		"Groups":                  templateGroups,
+		"Instance":                instance,
+		"ApiErrorResponseName":    r.helpers["ApiErrorResponse"],
+		"HasFormBody":             anyOperation(ops, func(op namedOperation) bool { return op.FormBody }),
+		"HasMultipartBody":        anyOperation(ops, func(op namedOperation) bool { return op.MultipartBody }),
+		"Servers":                 servers,
+		"ServerName":              r.helpers["Server"],
+		"ServerVariablesName":     r.helpers["ServerVariables"],
+		"ServerVariablesType":     serverVariables,
+		"HasOperationServers":     len(templatedServers) > 0,
+		"SecuritySchemes":         securitySchemes,
+		"CookieSchemes":           cookieSchemes,
+		"AdapterConfigName":       adapterConfigName,
+		"SecuritySchemeName":      r.helpers["SecurityScheme"],
+		"SecurityRequirementName": r.helpers["SecurityRequirement"],
+		"AuthRequestName":         r.helpers["AuthRequest"],
+		"AuthCredentialName":      r.helpers["AuthCredential"],
+		"ExportHelpers":           false,
+		"HasResponseHeaders":      anyOperation(ops, func(op namedOperation) bool { return op.HeaderKinds != "" }),
+		"FileDownloadName":        r.helpers["FileDownload"],
+		"DownloadProgressName":    r.helpers["DownloadProgress"],
+		"HasEventStreams":         anyOperation(ops, func(op namedOperation) bool { return op.Stream == "sse" }),
+		"HasNdjsonStreams":        anyOperation(ops, func(op namedOperation) bool { return op.Stream == "ndjson" }),
+		"ResponseTypeErrorName":   r.helpers["ResponseTypeError"],
+		"HasXMLDocuments": anyOperation(ops, func(op namedOperation) bool {
+			return op.ParseXML || slices.ContainsFunc(op.Accepts, func(alt acceptOverload) bool { return alt.ParseXML })
+		}),
 	}
 
-	return out.Bytes(), nil
+	return &generation{r: r, tmpl: tmpl, data: data, schemas: sortedSchemas, groups: groups, instance: instance}, nil
 }
 
+// operationServerPrefix is prepended to every operation path. A single plain server URL is inlined;
+// when the adapter picks among several servers or expands URL variables, the prefix is the server URL
+// createAdapter resolves.
 func operationServerPrefix(api *apitypes.OpenAPI) string {
+	if hasServerSelection(api.Servers) {
+		return "${serverBase}"
+	}
 	if len(api.Servers) == 0 {
 		return ""
 	}
@@ -259,12 +595,278 @@ func operationServerPrefix(api *apitypes.OpenAPI) string {
 	return strings.TrimRight(url, "/")
 }
 
-func resolveType(s *apitypes.Schema) string {
+// groupKey is the sub-adapter property of a tag: camelCase, with an all-caps first word lowercased,
+// so "Billing Accounts" becomes billingAccounts and "SSO" becomes sso.
+func groupKey(tag string) string {
+	if key := camelCase(tag); key != "" {
+		return key
+	}
+	return DefaultGroup
+}
+
+func camelCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if len(words) == 0 {
+		return ""
+	}
+	if strings.ToUpper(words[0]) == words[0] {
+		words[0] = strings.ToLower(words[0])
+	} else {
+		first, size := utf8.DecodeRuneInString(words[0])
+		words[0] = string(unicode.ToLower(first)) + words[0][size:]
+	}
+	return words[0] + apitypes.PascalCase(strings.Join(words[1:], " "))
+}
+
+// groupKeys maps the first tag of each operation to its group key. Tags whose keys would be equal to
+// another's, or to the DefaultGroup of untagged operations, when compared case-insensitively get a
+// numeric suffix in tag order, so no two tags share a group or an adapter file.
+func groupKeys(api *apitypes.OpenAPI, warn func(message string)) map[string]string {
+	tags := map[string]struct{}{}
+	taken := map[string]struct{}{}
+	for op := range api.Operations() {
+		if len(op.Operation.Tags) == 0 {
+			taken[DefaultGroup] = struct{}{}
+		} else {
+			tags[op.Operation.Tags[0]] = struct{}{}
+		}
+	}
+	keys := map[string]string{}
+	for _, tag := range apitypes.SortedKeys(tags) {
+		key := groupKey(tag)
+		keys[tag] = foldedName(key, taken)
+		if keys[tag] != key && warn != nil {
+			warn(fmt.Sprintf("tag %q is grouped as %s, since another group is already named %s", tag, keys[tag], key))
+		}
+	}
+	return keys
+}
+
+// foldedName returns name, or name with a numeric suffix when it equals a name in taken ignoring case,
+// and takes it. taken holds lowercased names, so the names it hands out can share a case-insensitive
+// file system.
+func foldedName(name string, taken map[string]struct{}) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := taken[strings.ToLower(unique)]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	taken[strings.ToLower(unique)] = struct{}{}
+	return unique
+}
+
+// reservedValueNames are the names a standalone operation function must not take: JavaScript reserved
+// words, the globals, helpers and exports of the generated module, and the parameters of adapter methods.
+var reservedValueNames = []string{
+	"await", "break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do",
+	"else", "enum", "export", "extends", "false", "finally", "for", "function", "if", "implements", "import",
+	"in", "instanceof", "interface", "let", "new", "null", "package", "private", "protected", "public",
+	"return", "static", "super", "switch", "this", "throw", "true", "try", "typeof", "var", "void", "while",
+	"with", "yield", "arguments", "eval", "undefined",
+	"Array", "Blob", "DOMParser", "Error", "File", "FormData", "Headers", "JSON", "Number", "Object",
+	"Promise", "RangeError", "String", "TextDecoderStream", "URLSearchParams", "btoa", "encodeURIComponent",
+	"buildQueryParams", "createAdapter", "servers", "serverUrl", "expandServerUrl", "SecuritySchemes", "SecurityRequirements",
+	"encodeFormBody", "encodeMultipartBody", "parseXmlResponse", "authorize", "applyCredential",
+	"checkResponseType", "readResponseHeaders", "parseHeader", "readDownload", "contentDispositionFilename", "readStreamLines",
+	"readEventStream", "readNdjsonStream",
+	"client", "config", "options", "query", "body", "headers",
+}
+
+// nameOperationFunctions names the standalone function of each operation: its operationId when that is
+// a free identifier, else the operationId camelCased, suffixed with Operation when the name is reserved,
+// a path parameter, or already taken by a type or another operation.
+func nameOperationFunctions(ops []namedOperation, types map[string]struct{}) {
+	taken := map[string]struct{}{}
+	for _, name := range reservedValueNames {
+		taken[name] = struct{}{}
+	}
+	for name := range types {
+		taken[name] = struct{}{}
+	}
+	for _, op := range ops {
+		for _, p := range op.PathParams {
+			taken[p.Name] = struct{}{}
+		}
+	}
+	for i, op := range ops {
+		if _, ok := taken[op.ID]; !ok && isTSIdentifier(op.ID) {
+			ops[i].Function = op.ID
+			taken[op.ID] = struct{}{}
+		}
+	}
+	for i, op := range ops {
+		if ops[i].Function != "" {
+			continue
+		}
+		base := camelCase(op.ID)
+		if !isTSIdentifier(base) {
+			base = "operation" + apitypes.PascalCase(base)
+		}
+		name := base
+		if _, ok := taken[name]; ok {
+			name = base + "Operation"
+		}
+		for n := 2; ; n++ {
+			if _, ok := taken[name]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%sOperation%d", base, n)
+		}
+		ops[i].Function = name
+		taken[name] = struct{}{}
+	}
+}
+
+// callArgs lists the arguments an adapter method passes on to its standalone function, matching argList.
+func callArgs(op namedOperation) string {
+	args := []string{}
+	for _, p := range op.PathParams {
+		args = append(args, p.Name)
+	}
+	if len(op.QueryParams) > 0 {
+		args = append(args, "query")
+	}
+	if op.HasBody {
+		args = append(args, "body")
+	}
+	if op.RawBody {
+		args = append(args, "headers")
+	}
+	return strings.Join(append(args, "options"), ", ")
+}
+
+// usesServerBase reports whether the URL of op is resolved against the server createAdapter selects.
+func usesServerBase(op namedOperation) bool {
+	return strings.Contains(op.DisplayPath, "${serverBase}")
+}
+
+// operationServers returns the servers declared on the operation, else on its path, or nil when the
+// operation uses the document's servers.
+func operationServers(api *apitypes.OpenAPI, path string, op *apitypes.Operation) []apitypes.Server {
+	if len(op.Servers) > 0 {
+		return op.Servers
+	}
+	if servers := api.PathServers[path]; len(servers) > 0 {
+		return servers
+	}
+	return nil
+}
+
+// plainServerURL is a server URL without a trailing slash, inlined into the URL of operations whose
+// server has no variables. Absolute URLs are sent as is, bypassing the client's base URL.
+func plainServerURL(server apitypes.Server) string {
+	return strings.TrimRight(strings.TrimSpace(server.URL), "/")
+}
+
+// isTemplatedServer reports whether the URL of server has variables, which are expanded at runtime.
+func isTemplatedServer(server apitypes.Server) bool {
+	return len(server.Variables) > 0 || strings.Contains(server.URL, "{")
+}
+
+// hasServerSelection reports whether servers leave a choice to the caller: several servers, or a URL
+// with variables.
+func hasServerSelection(servers []apitypes.Server) bool {
+	return len(servers) > 1 || (len(servers) == 1 && isTemplatedServer(servers[0]))
+}
+
+// serverLiteral renders a server as an entry of the exported servers table.
+func serverLiteral(server apitypes.Server) string {
+	fields := []string{"url: " + tsStringLiteral(strings.TrimSpace(server.URL))}
+	if server.Description != "" {
+		fields = append(fields, "description: "+tsStringLiteral(server.Description))
+	}
+	if len(server.Variables) > 0 {
+		variables := make([]string, 0, len(server.Variables))
+		for _, name := range apitypes.SortedKeys(server.Variables) {
+			variable := server.Variables[name]
+			if variable == nil {
+				continue
+			}
+			variableFields := []string{"default: " + tsStringLiteral(variable.Default)}
+			if len(variable.Enum) > 0 {
+				values := make([]string, 0, len(variable.Enum))
+				for _, value := range variable.Enum {
+					values = append(values, tsStringLiteral(value))
+				}
+				variableFields = append(variableFields, "enum: ["+strings.Join(values, ", ")+"]")
+			}
+			variables = append(variables, fmt.Sprintf("%s: { %s }", tsPropertyKey(name), strings.Join(variableFields, ", ")))
+		}
+		fields = append(fields, "variables: { "+strings.Join(variables, ", ")+" }")
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// serverVariablesType is the object type of the values a caller may give for server URL variables: the
+// enum values a variable allows wherever it is declared, or any string.
+func serverVariablesType(servers []apitypes.Server) string {
+	values := map[string][]string{}
+	open := map[string]bool{}
+	for _, server := range servers {
+		for name, variable := range server.Variables {
+			if variable == nil || len(variable.Enum) == 0 {
+				open[name] = true
+				values[name] = nil
+				continue
+			}
+			for _, value := range variable.Enum {
+				if !open[name] && !slices.Contains(values[name], value) {
+					values[name] = append(values[name], value)
+				}
+			}
+		}
+	}
+	props := make([]string, 0, len(values))
+	for _, name := range apitypes.SortedKeys(values) {
+		valueType := "string"
+		if !open[name] {
+			literals := slices.Sorted(slices.Values(values[name]))
+			for i, value := range literals {
+				literals[i] = tsStringLiteral(value)
+			}
+			valueType = strings.Join(literals, " | ")
+		}
+		props = append(props, fmt.Sprintf("%s?: %s", tsPropertyKey(name), valueType))
+	}
+	return "{ " + strings.Join(props, "; ") + " }"
+}
+
+func (r *typeResolver) expandType(s *apitypes.Schema) string {
 	if s == nil {
 		return "any"
 	}
+	if override, ok := tsTypeExtension(s); ok {
+		return r.useOverride(override)
+	}
 	if s.Ref != "" {
-		return extractRefName(s.Ref)
+		return r.schemaTypeName(extractRefName(s.Ref))
+	}
+	if s.Not != nil {
+		ownSchema := *s
+		ownSchema.Not = nil
+		if !hasOwnSchemaSurface(&ownSchema) {
+			return "any"
+		}
+		base := r.resolveType(&ownSchema)
+		if isExcludableSchema(s.Not) {
+			return "Exclude<" + base + ", " + r.resolveType(s.Not) + ">"
+		}
+		return base
+	}
+	if s.If != nil {
+		ownSchema := *s
+		ownSchema.If, ownSchema.Then, ownSchema.Else = nil, nil, nil
+		thenType, elseType := r.conditionalBranchType(&ownSchema, s.Then), r.conditionalBranchType(&ownSchema, s.Else)
+		if thenType == "" || elseType == "" {
+			return r.resolveType(&ownSchema)
+		}
+		branches := "(" + thenType + " | " + elseType + ")"
+		if hasOwnSchemaSurface(&ownSchema) {
+			return r.resolveType(&ownSchema) + " & " + branches
+		}
+		return branches
 	}
 	if len(s.Enum) > 0 {
 		types := make([]string, 0, len(s.Enum))
@@ -295,12 +897,12 @@ func resolveType(s *apitypes.Schema) string {
 	if len(s.AllOf) > 0 {
 		types := []string{}
 		for _, sub := range s.AllOf {
-			types = append(types, resolveType(sub))
+			types = append(types, r.resolveType(sub))
 		}
 		ownSchema := *s
 		ownSchema.AllOf = nil
 		if hasOwnSchemaSurface(&ownSchema) {
-			types = append(types, resolveType(&ownSchema))
+			types = append(types, r.resolveType(&ownSchema))
 		}
 		return strings.Join(types, " & ")
 	}
@@ -311,20 +913,23 @@ func resolveType(s *apitypes.Schema) string {
 		}
 		types := []string{}
 		for _, sub := range union {
-			types = append(types, resolveType(sub))
+			types = append(types, r.resolveType(sub))
 		}
 		return strings.Join(types, " | ")
 	}
 	typeVals := s.Type.Values
 	if len(typeVals) == 0 {
-		if len(s.Properties) > 0 || s.AdditionalProperties != nil {
+		if len(s.Properties) > 0 || hasDynamicProperties(s) {
 			typeVals = []string{"object"}
-		} else if s.Items != nil {
+		} else if s.Items != nil || len(s.PrefixItems) > 0 || s.Contains != nil {
 			typeVals = []string{"array"}
 		}
 	}
 
 	mapOne := func(t string) string {
+		if override, ok := r.formats[s.Format]; ok && s.Format != "" && (t == "string" || t == "integer" || t == "number") {
+			return r.useOverride(override)
+		}
 		switch t {
 		case "string":
 			if s.Format == "binary" {
@@ -338,9 +943,9 @@ func resolveType(s *apitypes.Schema) string {
 		case "null":
 			return "null"
 		case "array":
-			return "Array<" + resolveType(s.Items) + ">"
+			return r.resolveArrayType(s)
 		case "object":
-			return resolveObjectType(s)
+			return r.resolveObjectType(s)
 		default:
 			return "any"
 		}
@@ -400,6 +1005,14 @@ func tsStringLiteral(value string) string {
 	return fmt.Sprintf("%q", value)
 }
 
+// deprecatedTag is the inline JSDoc that marks a deprecated member of an object type literal.
+func deprecatedTag(deprecated bool) string {
+	if deprecated {
+		return "/** @deprecated */ "
+	}
+	return ""
+}
+
 func hasRequiredQueryParams(op namedOperation) bool {
 	for _, p := range op.QueryParams {
 		if p.Required {
@@ -414,21 +1027,86 @@ func extractRefName(ref string) string {
 	return parts[len(parts)-1]
 }
 
-func requestTypeForOperation(op *apitypes.Operation) string {
+func (r *typeResolver) requestTypeForOperation(op *apitypes.Operation) string {
 	if op == nil || op.RequestBody == nil {
 		return ""
 	}
-	schema := requestContentSchema(op.RequestBody.Content)
+	schema := r.media.requestSchema(op.RequestBody.Content)
 	if schema == nil {
 		return ""
 	}
 	if isBinarySchema(schema) {
 		return "BodyInit"
 	}
-	return resolveType(schema)
+	return r.resolveType(schema)
+}
+
+// responseBodyType is the TypeScript type of a response body of mediaType once decoded.
+func (r *typeResolver) responseBodyType(mediaType string, schema *apitypes.Schema) string {
+	switch {
+	case responseDecoding(mediaType, schema) == "arrayBuffer":
+		return "ArrayBuffer"
+	case isBinarySchema(schema):
+		return "Blob"
+	case isXMLDocument(mediaType, schema):
+		return "Document"
+	}
+	return r.resolveType(schema)
+}
+
+// streamForOperation reports whether the operation's success response is a Server-Sent Events or
+// NDJSON stream, and the type of each item: itemSchema or x-stream-item, else the body schema. Event
+// data without a structured item schema is yielded as text.
+func (r *typeResolver) streamForOperation(op *apitypes.Operation) (string, string, bool) {
+	if op == nil {
+		return "", "", false
+	}
+	for _, code := range []string{"200", "201", "202", "203", "206", "default"} {
+		resp, ok := op.Responses[code]
+		if !ok || resp == nil {
+			continue
+		}
+		mediaType, ok := r.media.responseMediaType(resp.Content)
+		if !ok {
+			continue
+		}
+		format := streamFormat(mediaType)
+		if format == "" {
+			return "", "", false
+		}
+		item := resp.Content[mediaType].StreamItemSchema()
+		if item == nil {
+			item = resp.Content[mediaType].Schema
+		}
+		switch {
+		case format == "sse" && (item == nil || (item.Ref == "" && item.Type.Has("string"))):
+			return format, "string", true
+		case item == nil:
+			return format, "unknown", false
+		}
+		return format, r.resolveType(item), false
+	}
+	return "", "", false
+}
+
+// responseDecodingForOperation returns how the client should read the body of the response that
+// determines the operation's type, and whether that body is XML to parse into a Document.
+func (r *typeResolver) responseDecodingForOperation(op *apitypes.Operation) (string, bool) {
+	if op == nil {
+		return "", false
+	}
+	for _, code := range []string{"200", "201", "202", "203", "206", "default"} {
+		if resp, ok := op.Responses[code]; ok && resp != nil {
+			if mediaType, ok := r.media.responseMediaType(resp.Content); ok {
+				schema := resp.Content[mediaType].Schema
+				return responseDecoding(mediaType, schema), isXMLDocument(mediaType, schema)
+			}
+		}
+	}
+	return "", false
 }
 
-func responseTypeForOperation(op *apitypes.Operation) string {
+func (r *typeResolver) responseTypeForOperation(op *apitypes.Operation) string {
 	if op == nil {
 		return "any"
 	}
@@ -438,12 +1116,8 @@ func responseTypeForOperation(op *apitypes.Operation) string {
 			if code == "204" {
 				return "boolean"
 			}
-			schema := responseContentSchema(resp.Content)
-			if schema != nil {
-				if isBinarySchema(schema) {
-					return "Blob"
-				}
-				return resolveType(schema)
+			if mediaType, ok := r.media.responseMediaType(resp.Content); ok {
+				return r.responseBodyType(mediaType, resp.Content[mediaType].Schema)
 			}
 		}
 	}
@@ -453,9 +1127,9 @@ func responseTypeForOperation(op *apitypes.Operation) string {
 			if len(resp.Content) == 0 {
 				return "boolean"
 			}
-			schema := responseContentSchema(resp.Content)
+			schema := r.media.responseSchema(resp.Content)
 			if schema != nil {
-				return resolveType(schema)
+				return r.resolveType(schema)
 			}
 			return "boolean"
 		}
@@ -464,89 +1138,760 @@ func responseTypeForOperation(op *apitypes.Operation) string {
 	return "any"
 }
 
-func requestContentSchema(content map[string]*apitypes.MediaType) *apitypes.Schema {
-	if content == nil {
+// successTypesForOperation returns one entry per literal 2xx status when an operation declares more
+// than one, so the adapter can return a union discriminated on `status`. It returns nil otherwise.
+func (r *typeResolver) successTypesForOperation(op *apitypes.Operation) []statusType {
+	if op == nil {
 		return nil
 	}
-	if media, ok := content["application/json"]; ok && media != nil && media.Schema != nil {
-		return media.Schema
+
+	codes := []string{}
+	for code, resp := range op.Responses {
+		if resp != nil && strings.HasPrefix(code, "2") && isStatusLiteral(code) {
+			codes = append(codes, code)
+		}
 	}
-	keys := make([]string, 0, len(content))
-	for key := range content {
-		keys = append(keys, key)
+	if len(codes) < 2 {
+		return nil
 	}
-	sort.Strings(keys)
-	for _, key := range keys {
-		media := content[key]
-		if media != nil && media.Schema != nil {
-			return media.Schema
+	sort.Strings(codes)
+
+	successTypes := make([]statusType, 0, len(codes))
+	for _, code := range codes {
+		bodyType := "boolean"
+		content := op.Responses[code].Content
+		if mediaType, ok := r.media.responseMediaType(content); ok && code != "204" {
+			bodyType = r.responseBodyType(mediaType, content[mediaType].Schema)
 		}
+		successTypes = append(successTypes, statusType{Status: code, Type: bodyType})
 	}
-	return nil
+	return successTypes
 }
 
-func responseContentSchema(content map[string]apitypes.MediaType) *apitypes.Schema {
-	if content == nil {
-		return nil
-	}
-	if media, ok := content["application/json"]; ok && media.Schema != nil {
-		return media.Schema
+// unionOfStatusTypes is the body type passed to the client call: the distinct success body types
+// joined in status order.
+func unionOfStatusTypes(statusTypes []statusType) string {
+	seen := map[string]struct{}{}
+	types := []string{}
+	for _, st := range statusTypes {
+		if _, ok := seen[st.Type]; ok {
+			continue
+		}
+		seen[st.Type] = struct{}{}
+		types = append(types, st.Type)
 	}
-	keys := make([]string, 0, len(content))
-	for key := range content {
-		keys = append(keys, key)
+	return strings.Join(types, " | ")
+}
+
+// acceptOverloadsForOperation returns one overload per less-preferred media type of the first success
+// response that declares several, each typed from that media type's own schema.
+func (r *typeResolver) acceptOverloadsForOperation(op *apitypes.Operation, errorTypes []string, headers string) []acceptOverload {
+	if op == nil {
+		return nil
 	}
-	sort.Strings(keys)
-	for _, key := range keys {
-		media := content[key]
-		if media.Schema != nil {
-			return media.Schema
+	for _, code := range []string{"200", "201", "202", "203", "206"} {
+		resp, ok := op.Responses[code]
+		if !ok || resp == nil {
+			continue
+		}
+		mediaTypes := r.media.responseMediaTypes(resp.Content)
+		if len(mediaTypes) < 2 {
+			return nil
 		}
+		overloads := make([]acceptOverload, 0, len(mediaTypes)-1)
+		for _, mediaType := range mediaTypes[1:] {
+			schema := resp.Content[mediaType].Schema
+			returnType := returnTypeValue(namedOperation{ResponseType: r.responseBodyType(mediaType, schema), ErrorTypes: errorTypes, Headers: headers})
+			overloads = append(overloads, acceptOverload{
+				MediaType:  mediaType,
+				ReturnType: returnType,
+				Decoding:   responseDecoding(mediaType, schema),
+				ParseXML:   isXMLDocument(mediaType, schema),
+			})
+		}
+		return overloads
 	}
 	return nil
 }
 
-func hasOwnSchemaSurface(s *apitypes.Schema) bool {
-	if s == nil {
-		return false
+// errorTypesForOperation returns one ApiErrorResponse<Status, Body> per declared 4xx/5xx response, plus
+// `default` when the operation also declares a success or redirect response.
+func (r *typeResolver) errorTypesForOperation(op *apitypes.Operation) []string {
+	if op == nil {
+		return nil
 	}
-	return s.Ref != "" ||
-		len(s.Enum) > 0 ||
-		len(s.OneOf) > 0 ||
-		len(s.AnyOf) > 0 ||
-		!s.Type.IsEmpty() ||
-		len(s.Properties) > 0 ||
-		s.Items != nil ||
-		s.AdditionalProperties != nil
-}
-
-func isBinarySchema(s *apitypes.Schema) bool {
-	return s != nil && s.Type.Has("string") && s.Format == "binary"
-}
 
-func operationHasBinaryRequest(op *apitypes.Operation) bool {
-	return op != nil && op.RequestBody != nil && isBinarySchema(requestContentSchema(op.RequestBody.Content))
-}
+	hasSuccess := false
+	codes := []string{}
+	for code := range op.Responses {
+		switch {
+		case strings.HasPrefix(code, "2"), strings.HasPrefix(code, "3"):
+			hasSuccess = true
+		case strings.HasPrefix(code, "4"), strings.HasPrefix(code, "5"):
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if _, ok := op.Responses["default"]; ok && hasSuccess {
+		codes = append(codes, "default")
+	}
 
-func responseTypeValue(op namedOperation) string {
-	if op.ResponseType != "" {
-		return op.ResponseType
+	errorTypes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		status := code
+		if !isStatusLiteral(code) {
+			status = "number"
+		}
+		bodyType := "unknown"
+		if resp := op.Responses[code]; resp != nil {
+			if schema := r.media.responseSchema(resp.Content); schema != nil {
+				if isBinarySchema(schema) {
+					bodyType = "Blob"
+				} else {
+					bodyType = r.resolveType(schema)
+				}
+			}
+		}
+		errorTypes = append(errorTypes, fmt.Sprintf("%s<%s, %s>", r.helperTypeName("ApiErrorResponse"), status, bodyType))
 	}
-	return "any"
+	return errorTypes
 }
 
-func resolveParameter(api *apitypes.OpenAPI, param *apitypes.Parameter, seen map[string]struct{}) (*apitypes.Parameter, error) {
-	if param == nil {
-		return nil, fmt.Errorf("parameter is nil")
+// successHeadersForOperation returns the object type of the headers declared by the response that
+// determines the operation's type, fills in the headers of each entry of successTypes, and collects
+// how every declared success header is parsed, keyed by lowercase name.
+func (r *typeResolver) successHeadersForOperation(api *apitypes.OpenAPI, op *apitypes.Operation, successTypes []statusType) (string, map[string]string, error) {
+	kinds := map[string]string{}
+	if op == nil {
+		return "", kinds, nil
 	}
-	if param.Ref == "" {
-		return param, nil
+	for i := range successTypes {
+		headers, err := r.responseHeaders(api, op.Responses[successTypes[i].Status], kinds)
+		if err != nil {
+			return "", nil, err
+		}
+		successTypes[i].Headers = headers
 	}
-	const prefix = "#/components/parameters/"
-	if !strings.HasPrefix(param.Ref, prefix) {
-		return nil, fmt.Errorf("unsupported parameter ref %q", param.Ref)
+	if len(successTypes) > 0 {
+		return "", kinds, nil
 	}
-	name := strings.TrimPrefix(param.Ref, prefix)
+	for _, code := range []string{"200", "201", "202", "203", "204", "206", "default"} {
+		if resp, ok := op.Responses[code]; ok && resp != nil {
+			headers, err := r.responseHeaders(api, resp, kinds)
+			return headers, kinds, err
+		}
+	}
+	return "", kinds, nil
+}
+
+// responseHeaders renders the headers a response declares as an object type keyed by lowercase name
+// and records the kind each is parsed as. Content-Type is described by the media type instead.
+func (r *typeResolver) responseHeaders(api *apitypes.OpenAPI, resp *apitypes.Response, kinds map[string]string) (string, error) {
+	if resp == nil {
+		return "", nil
+	}
+	props := []string{}
+	for _, name := range apitypes.SortedKeys(resp.Headers) {
+		if strings.EqualFold(name, "content-type") {
+			continue
+		}
+		header, err := resolveHeader(api, resp.Headers[name], map[string]struct{}{})
+		if err != nil {
+			return "", err
+		}
+		key := strings.ToLower(name)
+		kind := headerKind(api, header.Schema)
+		kinds[key] = kind
+		// A required header is always exposed but still reads as undefined when the server leaves it
+		// out or sends a value that does not parse.
+		prop := fmt.Sprintf("%s?: %s", tsPropertyKey(key), r.headerType(header.Schema, kind))
+		if header.Required {
+			prop = fmt.Sprintf("%s: %s | undefined", tsPropertyKey(key), r.headerType(header.Schema, kind))
+		}
+		props = append(props, prop)
+	}
+	if len(props) == 0 {
+		return "", nil
+	}
+	return "{ " + strings.Join(props, "; ") + " }", nil
+}
+
+// headerKind is how readResponseHeaders parses a header value: "number", "boolean" or "string", with
+// a "[]" suffix for comma-separated arrays.
+func headerKind(api *apitypes.OpenAPI, s *apitypes.Schema) string {
+	if s != nil && s.Ref != "" {
+		s = api.Components.Schemas[extractRefName(s.Ref)]
+	}
+	switch {
+	case s == nil:
+		return "string"
+	case s.Type.Has("integer"), s.Type.Has("number"):
+		return "number"
+	case s.Type.Has("boolean"):
+		return "boolean"
+	case s.Type.Has("array") && s.Items != nil && s.Items.Schema != nil:
+		return headerKind(api, s.Items.Schema) + "[]"
+	}
+	return "string"
+}
+
+// headerType is the TypeScript type of a parsed header; values without a scalar schema stay strings.
+func (r *typeResolver) headerType(s *apitypes.Schema, kind string) string {
+	if s == nil || (kind == "string" && s.Ref == "" && len(s.Enum) == 0 && !s.Type.Has("string")) {
+		return "string"
+	}
+	return r.resolveType(s)
+}
+
+// headerKindsLiteral renders the kinds collected by responseHeaders as the literal readResponseHeaders
+// takes, or "" when no success response declares headers.
+func headerKindsLiteral(kinds map[string]string) string {
+	if len(kinds) == 0 {
+		return ""
+	}
+	entries := make([]string, 0, len(kinds))
+	for _, name := range apitypes.SortedKeys(kinds) {
+		entries = append(entries, fmt.Sprintf("%s: %s", tsPropertyKey(name), tsStringLiteral(kinds[name])))
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+// withResponseHeaders wraps a request expression so the declared success headers are readable as
+// typed properties of response.headers.
+func withResponseHeaders(op namedOperation, expr string) string {
+	if op.HeaderKinds == "" {
+		return expr
+	}
+	return fmt.Sprintf("readResponseHeaders(%s, %s)", expr, op.HeaderKinds)
+}
+
+// isCookieScheme reports whether scheme is an API key sent in a cookie, which the browser must send
+// itself: fetch treats Cookie as a forbidden request header.
+func isCookieScheme(scheme *apitypes.SecurityScheme) bool {
+	return scheme != nil && scheme.Type == "apiKey" && scheme.In == "cookie"
+}
+
+// securitySchemeLiteral renders the fields of a security scheme that applyCredential reads.
+func securitySchemeLiteral(scheme *apitypes.SecurityScheme) string {
+	if scheme == nil {
+		return "{}"
+	}
+	fields := []string{"type: " + tsStringLiteral(scheme.Type)}
+	switch scheme.Type {
+	case "apiKey":
+		fields = append(fields, "name: "+tsStringLiteral(scheme.Name), "in: "+tsStringLiteral(scheme.In))
+	case "http":
+		fields = append(fields, "scheme: "+tsStringLiteral(scheme.Scheme))
+		if scheme.BearerFormat != "" {
+			fields = append(fields, "bearerFormat: "+tsStringLiteral(scheme.BearerFormat))
+		}
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// securityRequirementsLiteral renders requirements as a SecurityRequirements entry and reports whether
+// any of them names a scheme.
+func securityRequirementsLiteral(requirements []apitypes.SecurityRequirement) (string, bool) {
+	secured := false
+	entries := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		schemes := make([]string, 0, len(requirement))
+		for _, name := range apitypes.SortedKeys(requirement) {
+			scopes := make([]string, 0, len(requirement[name]))
+			for _, scope := range requirement[name] {
+				scopes = append(scopes, tsStringLiteral(scope))
+			}
+			schemes = append(schemes, fmt.Sprintf("%s: [%s]", tsPropertyKey(name), strings.Join(scopes, ", ")))
+		}
+		if len(schemes) == 0 {
+			entries = append(entries, "{}")
+			continue
+		}
+		secured = true
+		entries = append(entries, "{ "+strings.Join(schemes, ", ")+" }")
+	}
+	return "[" + strings.Join(entries, ", ") + "]", secured
+}
+
+func isStatusLiteral(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionalBranchType renders a then or else branch of base, or "" when the branch adds nothing a
+// TypeScript type can express. A branch without a type surface of its own, such as `required: [card]`,
+// refines base: the properties of base it requires become non-optional.
+func (r *typeResolver) conditionalBranchType(base *apitypes.Schema, branch *apitypes.Schema) string {
+	if branch == nil {
+		return ""
+	}
+	if hasOwnSchemaSurface(branch) || len(branch.AllOf) > 0 {
+		if branchType := r.resolveType(branch); branchType != "any" {
+			return branchType
+		}
+		return ""
+	}
+	required := slices.Clone(branch.Required)
+	slices.Sort(required)
+	props := []string{}
+	for _, name := range slices.Compact(required) {
+		propType := "unknown"
+		if prop, ok := base.Properties[name]; ok {
+			if prop != nil && hidden(prop.Extensions, r.includeInternal) {
+				continue
+			}
+			propType = r.resolveType(prop)
+		}
+		props = append(props, fmt.Sprintf("%s: %s", tsPropertyKey(name), propType))
+	}
+	if len(props) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(props, "; ") + " }"
+}
+
+func hasOwnSchemaSurface(s *apitypes.Schema) bool {
+	if s == nil {
+		return false
+	}
+	return s.Ref != "" ||
+		len(s.Enum) > 0 ||
+		len(s.OneOf) > 0 ||
+		len(s.AnyOf) > 0 ||
+		!s.Type.IsEmpty() ||
+		len(s.Properties) > 0 ||
+		s.Items != nil ||
+		len(s.PrefixItems) > 0 ||
+		s.Not != nil ||
+		s.If != nil ||
+		hasDynamicProperties(s)
+}
+
+// isExcludableSchema reports whether a `not` schema is a literal enum or primitive type that
+// TypeScript's Exclude can subtract exactly.
+func isExcludableSchema(s *apitypes.Schema) bool {
+	if s == nil || s.Ref != "" || s.Format != "" || s.Not != nil || s.If != nil {
+		return false
+	}
+	if len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 || len(s.Properties) > 0 || s.Items != nil || len(s.PrefixItems) > 0 || hasDynamicProperties(s) {
+		return false
+	}
+	if len(s.Enum) > 0 {
+		return true
+	}
+	if s.Type.IsEmpty() {
+		return false
+	}
+	for _, t := range s.Type.Values {
+		switch t {
+		case "string", "number", "boolean", "null":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// schemaWarnings reports schema constructs that resolveType can only approximate.
+func schemaWarnings(api *apitypes.OpenAPI) []string {
+	warnings := []string{}
+	seen := map[*apitypes.Schema]struct{}{}
+	var walk func(path string, s *apitypes.Schema)
+	walk = func(path string, s *apitypes.Schema) {
+		if s == nil {
+			return
+		}
+		if _, ok := seen[s]; ok {
+			return
+		}
+		seen[s] = struct{}{}
+
+		if s.If != nil {
+			expressible := func(branch *apitypes.Schema) bool {
+				return hasOwnSchemaSurface(branch) || len(branch.AllOf) > 0 || len(branch.Required) > 0
+			}
+			if s.Then != nil && s.Else != nil && (!expressible(s.Then) || !expressible(s.Else)) {
+				warnings = append(warnings, fmt.Sprintf("%s: if/then/else branch without a type or required properties cannot be expressed in TypeScript; condition ignored", path))
+			} else if s.Then != nil && s.Else != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: if/then/else approximated as a union of the then and else branches", path))
+			} else if s.Then != nil || s.Else != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: if/then/else without both branches cannot be expressed in TypeScript; condition ignored", path))
+			}
+		}
+		if s.Not != nil {
+			ownSchema := *s
+			ownSchema.Not = nil
+			if !hasOwnSchemaSurface(&ownSchema) {
+				warnings = append(warnings, fmt.Sprintf("%s: not without a base type cannot be expressed in TypeScript; emitted as any", path))
+			} else if !isExcludableSchema(s.Not) {
+				warnings = append(warnings, fmt.Sprintf("%s: not is only translated for literal enums and primitive types; constraint ignored", path))
+			}
+		}
+		for _, sub := range s.Subschemas() {
+			walk(path, sub)
+		}
+	}
+
+	for _, name := range apitypes.SortedKeys(api.Components.Schemas) {
+		walk(fmt.Sprintf("components.schemas[%q]", name), api.Components.Schemas[name])
+	}
+	for op := range api.Operations() {
+		for schema := range op.Operation.Schemas() {
+			walk(op.Location()+"."+schema.Location, schema.Schema)
+		}
+	}
+
+	return warnings
+}
+
+func hasDynamicProperties(s *apitypes.Schema) bool {
+	return s.AdditionalProperties != nil ||
+		len(s.PatternProperties) > 0 ||
+		s.PropertyNames != nil ||
+		s.UnevaluatedProperties != nil
+}
+
+func isBinarySchema(s *apitypes.Schema) bool {
+	return s != nil && s.Type.Has("string") && s.Format == "binary"
+}
+
+func (r *typeResolver) operationHasBinaryRequest(op *apitypes.Operation) bool {
+	return op != nil && op.RequestBody != nil && isBinarySchema(r.media.requestSchema(op.RequestBody.Content))
+}
+
+// argList renders the adapter method parameters; optionsArg is the trailing options parameter. When
+// optionsArg is required, optional parameters before it are rendered as required `T | undefined`.
+func (r *typeResolver) argList(op namedOperation, optionsArg string) string {
+	optionalArg := func(name, paramType string) string {
+		if strings.HasPrefix(optionsArg, "options: ") {
+			return fmt.Sprintf("%s: %s | undefined", name, paramType)
+		}
+		return fmt.Sprintf("%s?: %s", name, paramType)
+	}
+	args := []string{}
+	for _, p := range op.PathParams {
+		paramType := r.resolveType(p.Schema)
+		if !p.Required {
+			paramType += " | undefined"
+		}
+		args = append(args, fmt.Sprintf("%s: %s", p.Name, paramType))
+	}
+	if len(op.QueryParams) > 0 {
+		queryProps := []string{}
+		for _, p := range op.QueryParams {
+			paramType := r.resolveType(p.Schema)
+			optional := "?"
+			if p.Required {
+				optional = ""
+			}
+			queryProps = append(queryProps, deprecatedTag(p.Deprecated)+fmt.Sprintf("%s%s: %s", tsPropertyKey(p.Name), optional, paramType))
+		}
+		queryType := fmt.Sprintf("{ %s }", strings.Join(queryProps, "; "))
+		if hasRequiredQueryParams(op) {
+			args = append(args, fmt.Sprintf("query: %s", queryType))
+		} else {
+			args = append(args, optionalArg("query", queryType))
+		}
+	}
+	if op.HasBody {
+		bodyType := "any"
+		if op.RequestType != "" {
+			bodyType = op.RequestType
+		}
+		if op.BodyRequired {
+			args = append(args, fmt.Sprintf("body: %s", bodyType))
+		} else {
+			args = append(args, optionalArg("body", bodyType))
+		}
+	}
+	if op.RawBody {
+		args = append(args, optionalArg("headers", "HeadersInit"))
+	}
+	args = append(args, optionsArg)
+	return strings.Join(args, ", ")
+}
+
+func (r *typeResolver) requestOptionsType(op namedOperation, extra string) string {
+	fields := []string{"signal?: AbortSignal", "timeout?: number", "operationId?: string"}
+	if op.Download {
+		fields = append(fields, fmt.Sprintf("onProgress?: (progress: %s) => void", r.helperTypeName("DownloadProgress")))
+	}
+	if extra != "" {
+		fields = append(fields, extra)
+	}
+	return "{ " + strings.Join(fields, "; ") + " }"
+}
+
+// acceptCallBlock sends the request through client.request with an Accept header when the caller
+// asks for an alternative media type; it renders nothing for operations without accept overloads.
+func acceptCallBlock(op namedOperation, urlExpr string, optionsVar string) string {
+	if len(op.Accepts) == 0 {
+		return ""
+	}
+	headersInit := ""
+	if op.RawBody {
+		headersInit = "headers"
+	}
+	init, contentType := requestInit(op, "requestHeaders")
+
+	blocks := make([]string, 0, len(op.Accepts))
+	for _, alt := range op.Accepts {
+		decoding := alt.Decoding
+		if decoding == "" {
+			decoding = "json"
+		}
+		lines := []string{
+			fmt.Sprintf("if (options?.accept === %s) {", tsStringLiteral(alt.MediaType)),
+			fmt.Sprintf("    const requestHeaders = new Headers(%s);", headersInit),
+			fmt.Sprintf("    requestHeaders.set('Accept', %s);", tsStringLiteral(alt.MediaType)),
+		}
+		if contentType != "" {
+			lines = append(lines, fmt.Sprintf("    requestHeaders.set('Content-Type', %s);", tsStringLiteral(contentType)))
+		}
+		call := fmt.Sprintf("client.request<any>(%s, %s, { ...%s, responseType: %s as const })", urlExpr, init, optionsVar, tsStringLiteral(decoding))
+		if op.Secured {
+			call = fmt.Sprintf("authorize(config.auth, %s, %s, requestHeaders).then(({ url, headers: requestHeaders }) => client.request<any>(url, %s, { ...%s, responseType: %s as const }))", tsStringLiteral(op.ID), urlExpr, init, optionsVar, tsStringLiteral(decoding))
+		}
+		call = checkDecoding(alt.Decoding, call)
+		if alt.ParseXML {
+			call += ".then(parseXmlResponse)"
+		}
+		lines = append(lines, "    return "+withResponseHeaders(op, call)+";", "  }")
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n  ")
+}
+
+// requestInit renders the RequestInit that sends op's body through client.request with the given
+// headers expression, and the Content-Type to set on those headers, if any.
+func requestInit(op namedOperation, headers string) (string, string) {
+	switch {
+	case op.RawBody:
+		return fmt.Sprintf("{ method: %q, headers: %s, body }", httpMethod(op), headers), ""
+	case op.FormBody:
+		return fmt.Sprintf("{ method: %q, headers: %s, body: %s }", httpMethod(op), headers, encodedBodyExpr(op)), "application/x-www-form-urlencoded"
+	case op.MultipartBody:
+		return fmt.Sprintf("{ method: %q, headers: %s, body: %s }", httpMethod(op), headers, encodedBodyExpr(op)), ""
+	case op.HasBody && isJSONMediaType(op.RequestMediaType):
+		return fmt.Sprintf("{ method: %q, headers: %s, body: body === undefined ? undefined : JSON.stringify(body) }", httpMethod(op), headers), op.RequestMediaType
+	case op.HasBody:
+		contentType := op.RequestMediaType
+		if mediaTypeMatches("multipart/*", op.RequestMediaType) {
+			contentType = ""
+		}
+		return fmt.Sprintf("{ method: %q, headers: %s, body: body as BodyInit | undefined }", httpMethod(op), headers), contentType
+	}
+	return fmt.Sprintf("{ method: %q, headers: %s }", httpMethod(op), headers), ""
+}
+
+// authorizedCallExpr sends op through client.request with the credentials the auth hook supplies for
+// its security requirements.
+func authorizedCallExpr(op namedOperation, urlExpr string, optionsVar string, typeArgs string) string {
+	if typeArgs == "" {
+		typeArgs = "<" + responseTypeValue(op) + ">"
+	}
+	headersInit := ""
+	init, contentType := requestInit(op, "requestHeaders")
+	switch {
+	case op.RawBody:
+		headersInit = ", headers"
+	case contentType != "":
+		headersInit = fmt.Sprintf(", { 'Content-Type': %s }", tsStringLiteral(contentType))
+	}
+	return fmt.Sprintf("authorize(config.auth, %s, %s%s).then(({ url, headers: requestHeaders }) => client.request%s(url, %s, %s))", tsStringLiteral(op.ID), urlExpr, headersInit, typeArgs, init, optionsVar)
+}
+
+// httpMethod is the HTTP method of op; the client method for DELETE is `del`.
+func httpMethod(op namedOperation) string {
+	if op.Method == "del" {
+		return "DELETE"
+	}
+	return strings.ToUpper(op.Method)
+}
+
+func isFormMediaType(mediaType string) bool {
+	return mediaTypeMatches("application/x-www-form-urlencoded", mediaType)
+}
+
+func isMultipartMediaType(mediaType string) bool {
+	return mediaTypeMatches("multipart/form-data", mediaType)
+}
+
+// encodedBodyExpr converts the typed body of a form-urlencoded or multipart operation into its wire form.
+func encodedBodyExpr(op namedOperation) string {
+	encoder := "encodeFormBody"
+	if op.MultipartBody {
+		encoder = "encodeMultipartBody"
+	}
+	if op.BodyEncoding == "" {
+		return encoder + "(body)"
+	}
+	return fmt.Sprintf("%s(body, %s)", encoder, op.BodyEncoding)
+}
+
+// encodingLiteral renders an OpenAPI encoding object as the TypeScript literal the body encoders read,
+// or "" when no property declares anything beyond the defaults.
+func encodingLiteral(encoding map[string]*apitypes.Encoding) string {
+	entries := []string{}
+	for _, name := range apitypes.SortedKeys(encoding) {
+		enc := encoding[name]
+		if enc == nil {
+			continue
+		}
+		fields := []string{}
+		// A list or wildcard names the acceptable part types rather than the type to send.
+		if enc.ContentType != "" && !strings.ContainsAny(enc.ContentType, ",*") {
+			fields = append(fields, "contentType: "+tsStringLiteral(enc.ContentType))
+		}
+		if enc.Style != "" {
+			fields = append(fields, "style: "+tsStringLiteral(enc.Style))
+		}
+		if enc.Explode != nil {
+			fields = append(fields, fmt.Sprintf("explode: %t", *enc.Explode))
+		}
+		if len(fields) > 0 {
+			entries = append(entries, fmt.Sprintf("%s: { %s }", tsPropertyKey(name), strings.Join(fields, ", ")))
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+func anyOperation(ops []namedOperation, match func(op namedOperation) bool) bool {
+	for _, op := range ops {
+		if match(op) {
+			return true
+		}
+	}
+	return false
+}
+
+// methodReturnType is the declared return type of an adapter method.
+func methodReturnType(op namedOperation) string {
+	if op.Stream != "" {
+		return "AsyncIterable<" + responseTypeValue(op) + ">"
+	}
+	return "Promise<" + returnTypeValue(op) + ">"
+}
+
+func responseTypeValue(op namedOperation) string {
+	if op.ResponseType != "" {
+		return op.ResponseType
+	}
+	return "any"
+}
+
+// returnTypeValue is the resolved value of an adapter method; declared error statuses become
+// ApiErrorResponse branches so callers can narrow on `ok` and `status`.
+func returnTypeValue(op namedOperation) string {
+	if len(op.SuccessTypes) > 0 {
+		branches := make([]string, 0, len(op.SuccessTypes)+len(op.ErrorTypes))
+		for _, success := range op.SuccessTypes {
+			branches = append(branches, successBranch(success.Type, len(op.ErrorTypes) > 0, "status: "+success.Status, success.Headers))
+		}
+		return strings.Join(append(branches, op.ErrorTypes...), " | ")
+	}
+
+	success := successBranch(responseTypeValue(op), len(op.ErrorTypes) > 0, "", op.Headers)
+	if len(op.ErrorTypes) == 0 {
+		return success
+	}
+	return success + " | " + strings.Join(op.ErrorTypes, " | ")
+}
+
+// successBranch is one success member of a return type: the FetchResponse narrowed by `ok` when the
+// operation also declares errors, by `status` when it declares several successes, and with typed headers.
+func successBranch(bodyType string, hasErrors bool, status string, headers string) string {
+	fields := []string{}
+	if hasErrors {
+		fields = append(fields, "ok: true")
+	}
+	if status != "" {
+		fields = append(fields, status)
+	}
+	if headers != "" {
+		fields = append(fields, "headers: Headers & "+headers)
+	}
+	if len(fields) == 0 {
+		return "FetchResponse<" + bodyType + ">"
+	}
+	return fmt.Sprintf("FetchResponse<%s> & { %s }", bodyType, strings.Join(fields, "; "))
+}
+
+// clientCallExpr is the request expression of op; secured operations are authorized through the
+// auth hook when one is configured.
+func clientCallExpr(op namedOperation, urlExpr string, optionsVar string, typeArgs string) string {
+	call := plainCallExpr(op, urlExpr, optionsVar, typeArgs)
+	if op.Secured {
+		call = fmt.Sprintf("(config.auth ? %s : %s)", authorizedCallExpr(op, urlExpr, optionsVar, typeArgs), call)
+	}
+	return checkDecoding(op.Decoding, call)
+}
+
+// checkDecoding wraps call in checkResponseType when decoding asks the client for a text, Blob or
+// ArrayBuffer body. Streams are checked by the helpers that read them.
+func checkDecoding(decoding string, call string) string {
+	if !checkedDecoding(decoding) {
+		return call
+	}
+	return fmt.Sprintf("checkResponseType(%s, %s)", call, tsStringLiteral(decoding))
+}
+
+func checkedDecoding(decoding string) bool {
+	return decoding == "text" || decoding == "blob" || decoding == "arrayBuffer"
+}
+
+// usesCheckedDecoding reports whether op, or one of its accept overloads, checks its body against the
+// responseType it asked for: through checkDecoding, or for a stream in the helper that reads it.
+func usesCheckedDecoding(op namedOperation) bool {
+	return op.Decoding == "stream" || checkedDecoding(op.Decoding) || slices.ContainsFunc(op.Accepts, func(alt acceptOverload) bool { return checkedDecoding(alt.Decoding) })
+}
+
+func plainCallExpr(op namedOperation, urlExpr string, optionsVar string, typeArgs string) string {
+	if op.RawBody {
+		return fmt.Sprintf("client.request<%s>(%s, { method: %q, headers, body }, %s)", responseTypeValue(op), urlExpr, httpMethod(op), optionsVar)
+	}
+	if op.FormBody {
+		return fmt.Sprintf("client.request<%s>(%s, { method: %q, headers: { 'Content-Type': 'application/x-www-form-urlencoded' }, body: %s }, %s)", responseTypeValue(op), urlExpr, httpMethod(op), encodedBodyExpr(op), optionsVar)
+	}
+	if op.MultipartBody {
+		return fmt.Sprintf("client.request<%s>(%s, { method: %q, body: %s }, %s)", responseTypeValue(op), urlExpr, httpMethod(op), encodedBodyExpr(op), optionsVar)
+	}
+	bodyArg := "undefined"
+	if op.HasBody {
+		bodyArg = "body"
+	}
+	switch op.Method {
+	case "get", "del", "head":
+		return fmt.Sprintf("client.%s%s(%s, undefined, %s)", op.Method, typeArgs, urlExpr, optionsVar)
+	case "post", "put", "patch":
+		return fmt.Sprintf("client.%s%s(%s, %s, undefined, %s)", op.Method, typeArgs, urlExpr, bodyArg, optionsVar)
+	default:
+		return fmt.Sprintf("client.%s%s(%s, %s, %s)", op.Method, typeArgs, urlExpr, bodyArg, optionsVar)
+	}
+}
+
+func resolveParameter(api *apitypes.OpenAPI, param *apitypes.Parameter, seen map[string]struct{}) (*apitypes.Parameter, error) {
+	if param == nil {
+		return nil, fmt.Errorf("parameter is nil")
+	}
+	if param.Ref == "" {
+		return param, nil
+	}
+	const prefix = "#/components/parameters/"
+	if !strings.HasPrefix(param.Ref, prefix) {
+		return nil, fmt.Errorf("unsupported parameter ref %q", param.Ref)
+	}
+	name := strings.TrimPrefix(param.Ref, prefix)
 	if _, ok := seen[name]; ok {
 		return nil, fmt.Errorf("cyclic parameter ref %q", param.Ref)
 	}
@@ -554,89 +1899,328 @@ func resolveParameter(api *apitypes.OpenAPI, param *apitypes.Parameter, seen map
 	if !ok {
 		return nil, fmt.Errorf("unresolved parameter ref %q", param.Ref)
 	}
-	seen[name] = struct{}{}
-	return resolveParameter(api, component, seen)
+	seen[name] = struct{}{}
+	return resolveParameter(api, component, seen)
+}
+
+func resolveHeader(api *apitypes.OpenAPI, header *apitypes.Header, seen map[string]struct{}) (*apitypes.Header, error) {
+	if header == nil {
+		return nil, fmt.Errorf("header is nil")
+	}
+	if header.Ref == "" {
+		return header, nil
+	}
+	const prefix = "#/components/headers/"
+	if !strings.HasPrefix(header.Ref, prefix) {
+		return nil, fmt.Errorf("unsupported header ref %q", header.Ref)
+	}
+	name := strings.TrimPrefix(header.Ref, prefix)
+	if _, ok := seen[name]; ok {
+		return nil, fmt.Errorf("cyclic header ref %q", header.Ref)
+	}
+	component, ok := api.Components.Headers[name]
+	if !ok {
+		return nil, fmt.Errorf("unresolved header ref %q", header.Ref)
+	}
+	seen[name] = struct{}{}
+	return resolveHeader(api, component, seen)
+}
+
+// resolveArrayType emits a tuple for prefixItems; it stays open (...T[]) unless items is false or maxItems caps it.
+func (r *typeResolver) resolveArrayType(s *apitypes.Schema) string {
+	if len(s.PrefixItems) == 0 {
+		if s.Items.IsFalse() {
+			return "[]"
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			return "Array<" + r.resolveType(s.Items.Schema) + ">"
+		}
+		return "Array<any>"
+	}
+
+	// Without minItems an empty array is valid, so every element past minItems is optional, and
+	// elements past maxItems can never be present.
+	prefixItems := s.PrefixItems
+	if s.MaxItems != nil && *s.MaxItems < len(prefixItems) {
+		prefixItems = prefixItems[:max(*s.MaxItems, 0)]
+	}
+	minItems := 0
+	if s.MinItems != nil {
+		minItems = *s.MinItems
+	}
+	elements := make([]string, 0, len(prefixItems)+1)
+	for i, sub := range prefixItems {
+		element := r.resolveType(sub)
+		if i >= minItems {
+			if strings.ContainsAny(element, " |&") {
+				element = "(" + element + ")"
+			}
+			element += "?"
+		}
+		elements = append(elements, element)
+	}
+
+	closed := s.Items.IsFalse() || (s.MaxItems != nil && *s.MaxItems <= len(s.PrefixItems))
+	if !closed {
+		rest := "any"
+		if s.Items != nil && s.Items.Schema != nil {
+			rest = r.resolveType(s.Items.Schema)
+		}
+		if isTSIdentifier(rest) {
+			elements = append(elements, "..."+rest+"[]")
+		} else {
+			elements = append(elements, "...Array<"+rest+">")
+		}
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (r *typeResolver) resolveObjectType(s *apitypes.Schema) string {
+	props := []string{}
+	for _, name := range r.propertyKeys(s) {
+		prop := s.Properties[name]
+		optional := "?"
+		if containsString(s.Required, name) {
+			optional = ""
+		}
+		props = append(props, deprecatedTag(prop != nil && prop.Deprecated)+fmt.Sprintf("%s%s: %s", tsPropertyKey(name), optional, r.resolveType(prop)))
+	}
+
+	objectLiteral := ""
+	if len(props) > 0 {
+		objectLiteral = "{ " + strings.Join(props, "; ") + " }"
+	}
+
+	patternTypes := r.resolvePatternPropertyTypes(s.PatternProperties, "")
+
+	keyType, partialKeys := r.propertyNamesKeyType(s.PropertyNames)
+	record := func(valueType string) string {
+		if partialKeys {
+			return "Partial<Record<" + keyType + ", " + valueType + ">>"
+		}
+		return "Record<" + keyType + ", " + valueType + ">"
+	}
+
+	additional := s.AdditionalProperties
+	if additional == nil {
+		additional = s.UnevaluatedProperties
+	}
+	additionalType, additionalValueType := "", ""
+	if additional != nil {
+		if additional.Boolean != nil {
+			if *additional.Boolean {
+				additionalValueType = "any"
+			} else if objectLiteral == "" && len(patternTypes) == 0 {
+				additionalType = "Record<string, never>"
+			}
+		} else if additional.Schema != nil {
+			additionalValueType = r.resolveType(additional.Schema)
+		}
+	} else if s.PropertyNames != nil && objectLiteral == "" && len(patternTypes) == 0 {
+		additionalValueType = "any"
+	}
+	if additionalValueType != "" {
+		if len(patternTypes) > 0 && keyType == "string" && !partialKeys {
+			// Additional properties are keyed by string too, so their values join the widest Record.
+			patternTypes = r.resolvePatternPropertyTypes(s.PatternProperties, additionalValueType)
+		} else {
+			additionalType = record(additionalValueType)
+		}
+	}
+
+	parts := []string{}
+	if objectLiteral != "" {
+		parts = append(parts, objectLiteral)
+	}
+	parts = append(parts, patternTypes...)
+	if additionalType != "" {
+		parts = append(parts, additionalType)
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, " & ")
+	}
+
+	return "Record<string, any>"
+}
+
+// resolvePatternPropertyTypes folds patternProperties into one Record per widest key type: a
+// pattern whose keys another pattern's keys include (every key is a string, every x-a key an x- key)
+// unions its value type into that pattern's Record. Intersecting overlapping Records would type the
+// shared keys as the intersection of their values, usually never. A non-empty additionalValueType is
+// folded in under string keys.
+func (r *typeResolver) resolvePatternPropertyTypes(patterns map[string]*apitypes.Schema, additionalValueType string) []string {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	type entry struct {
+		key       patternKey
+		valueType string
+	}
+	entries := make([]entry, 0, len(patterns)+1)
+	for _, pattern := range apitypes.SortedKeys(patterns) {
+		entries = append(entries, entry{parsePatternKey(pattern), r.resolveType(patterns[pattern])})
+	}
+	if additionalValueType != "" {
+		entries = append(entries, entry{patternKey{}, additionalValueType})
+	}
+
+	roots := []patternKey{}
+	valueTypes := map[patternKey][]string{}
+	for _, e := range entries {
+		root := e.key
+		for _, other := range entries {
+			if other.key.covers(root) {
+				root = other.key
+			}
+		}
+		if _, ok := valueTypes[root]; !ok {
+			roots = append(roots, root)
+		}
+		if !containsString(valueTypes[root], e.valueType) {
+			valueTypes[root] = append(valueTypes[root], e.valueType)
+		}
+	}
+
+	records := make([]string, 0, len(roots))
+	for _, root := range roots {
+		records = append(records, "Record<"+root.tsType()+", "+strings.Join(valueTypes[root], " | ")+">")
+	}
+	return records
 }
 
-func resolveObjectType(s *apitypes.Schema) string {
-	propKeys := []string{}
-	for name := range s.Properties {
-		propKeys = append(propKeys, name)
+func (r *typeResolver) propertyNamesKeyType(s *apitypes.Schema) (string, bool) {
+	if s == nil {
+		return "string", false
 	}
-	sort.Strings(propKeys)
+	if len(s.Enum) > 0 {
+		return r.resolveType(s), true
+	}
+	if s.Pattern != "" {
+		return patternKeyType(s.Pattern), false
+	}
+	return "string", false
+}
 
-	props := []string{}
-	for _, name := range propKeys {
-		prop := s.Properties[name]
-		optional := "?"
-		if containsString(s.Required, name) {
-			optional = ""
-		}
-		props = append(props, fmt.Sprintf("%s%s: %s", tsPropertyKey(name), optional, resolveType(prop)))
+// patternKeyType maps an anchored literal regex such as ^x- or ^x-.*$ to a template literal
+// key type; anything it cannot translate exactly widens to string.
+func patternKeyType(pattern string) string {
+	return parsePatternKey(pattern).tsType()
+}
+
+// patternKey is the set of keys a patternProperties regex matches, as far as TypeScript can express
+// it: the keys starting with prefix, or only prefix itself when exact. The zero value is every string.
+type patternKey struct {
+	prefix string
+	exact  bool
+}
+
+// covers reports whether every key of other is also a key of k, with k strictly wider.
+func (k patternKey) covers(other patternKey) bool {
+	return !k.exact && k != other && strings.HasPrefix(other.prefix, k.prefix)
+}
+
+func (k patternKey) tsType() string {
+	switch {
+	case k.exact:
+		return tsStringLiteral(k.prefix)
+	case k.prefix == "":
+		return "string"
 	}
+	value := strings.ReplaceAll(k.prefix, `\`, `\\`)
+	value = strings.ReplaceAll(value, "$", `\$`)
+	value = strings.ReplaceAll(value, "`", "\\`")
+	return "`" + value + "${string}`"
+}
 
-	objectLiteral := ""
-	if len(props) > 0 {
-		objectLiteral = "{ " + strings.Join(props, "; ") + " }"
+func parsePatternKey(pattern string) patternKey {
+	if !strings.HasPrefix(pattern, "^") {
+		return patternKey{}
 	}
+	body := strings.TrimPrefix(pattern, "^")
 
-	additionalType := ""
-	if s.AdditionalProperties != nil {
-		if s.AdditionalProperties.Boolean != nil {
-			if *s.AdditionalProperties.Boolean {
-				additionalType = "Record<string, any>"
-			} else if objectLiteral == "" {
-				additionalType = "Record<string, never>"
-			}
-		} else if s.AdditionalProperties.Schema != nil {
-			additionalType = "Record<string, " + resolveType(s.AdditionalProperties.Schema) + ">"
+	exact := false
+	for _, suffix := range []string{".*$", ".+$", ".*", ".+"} {
+		if strings.HasSuffix(body, suffix) && !strings.HasSuffix(body, `\`+suffix) {
+			body = strings.TrimSuffix(body, suffix)
+			break
 		}
 	}
-
-	if objectLiteral != "" && additionalType != "" {
-		return objectLiteral + " & " + additionalType
+	if strings.HasSuffix(body, "$") && !strings.HasSuffix(body, `\$`) {
+		body = strings.TrimSuffix(body, "$")
+		exact = true
 	}
-	if objectLiteral != "" {
-		return objectLiteral
+
+	var literal strings.Builder
+	escaped := false
+	for _, r := range body {
+		if escaped {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return patternKey{}
+			}
+			literal.WriteRune(r)
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+		case '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|', '^', '$', '`':
+			return patternKey{}
+		default:
+			literal.WriteRune(r)
+		}
 	}
-	if additionalType != "" {
-		return additionalType
+	if escaped || literal.Len() == 0 {
+		return patternKey{}
 	}
-
-	return "Record<string, any>"
+	return patternKey{prefix: literal.String(), exact: exact}
 }
 
-const apiTemplate = `// Auto-generated by fetch-gen
+const apiTemplate = `
+{{- define "header"}}// Auto-generated by fetch-gen
 import type { FetchClient, FetchResponse } from '{{.Instance}}';
 import { buildQueryParams } from '{{.Instance}}';
-
-/**
- * Creates an API adapter with typed methods for all OpenAPI operations.
- *
- * @param client - The FetchClient instance to use for HTTP requests
- * @returns An object with typed methods for each API operation
- *
- * @example
- * ` + "```typescript" + `
- * import { createAdapter } from './generated';
- * import client from '@fgrzl/fetch';
- *
- * client.setBaseUrl('https://api.example.com');
- * const api = createAdapter(client);
- *
- * const response = await api.getUsers();
- * if (response.ok) {
- *   console.log(response.data);
- * }
- * ` + "```" + `
- */
-export function createAdapter(client: FetchClient): {
-{{- range $i, $op := .Ops}}
+{{- range typeImports}}
+import type { {{join .Names ", "}} } from '{{.Module}}';
+{{- end}}
+{{- end}}
+{{- template "helpers" .}}
+{{- template "functions" .Ops}}
+{{template "createAdapterDoc" .}}
+export function createAdapter(client: FetchClient{{if .AdapterConfigName}}, config: {{.AdapterConfigName}} = {}{{end}}): {
+{{- range .Groups}}
+  {{tsPropertyKey .Key}}: {
+{{- template "signatures" .Ops}}
+  };
+{{- else}}
+{{- template "signatures" .Ops}}
+{{- end}}
+} {
+  return {
+{{- range $g, $group := .Groups}}
+    {{tsPropertyKey $group.Key}}: {
+{{- template "methods" $group.Ops}}
+    }{{if ne (add $g 1) (lenGroups $.Groups)}},{{end}}
+{{- else}}
+{{- template "methods" .Ops}}
+{{- end}}
+  };
+}
+{{range .SortedSchemas }}
+{{- template "model" .}}
+{{end}}
+{{- define "signatures"}}
+{{- range $i, $op := .}}
   /**
-   * {{if $op.Description}}{{$op.Description}}{{else}}{{$op.Method | upper}} {{$op.DisplayPath}}{{end}}
+   * {{if $op.Description}}{{$op.Description}}{{else}}{{$op.Method | upper}} {{$op.DocPath}}{{end}}
    *
+{{- if $op.Deprecated}}
+   * @deprecated
+{{- end}}
 {{- range $param := $op.PathParams}}
-   * @param {{$param.Name}} - {{if $param.Description}}{{$param.Description}}{{else}}{{$param.Name}} parameter{{end}}
+   * @param {{$param.Name}} - {{if $param.Description}}{{$param.Description}}{{else}}{{$param.Name}} parameter{{end}}{{if $param.Deprecated}} (deprecated){{end}}
 {{- end}}
 {{- if hasQueryParams $op}}
    * @param query - Query parameters
@@ -644,43 +2228,558 @@ export function createAdapter(client: FetchClient): {
 {{- if $op.HasBody}}
    * @param body - Request body
 {{- end}}
-	 * @param options - Request options (signal, timeout, operationId)
-   * @returns Promise resolving to FetchResponse<{{responseType $op}}>
+	 * @param options - Request options (signal, timeout, operationId{{if $op.Download}}, onProgress{{end}}{{if $op.Accepts}}, accept{{end}})
+   * @returns {{if $op.Stream}}Async iterable of {{$op.ResponseType}} items; abort ` + "`options.signal`" + ` to stop the stream{{else}}Promise resolving to {{returnType $op}}{{end}}
    */
-	{{tsPropertyKey $op.ID}}: ({{argList $op}}) => Promise<FetchResponse<{{responseType $op}}>>;
+{{- if $op.Accepts}}
+	{{tsPropertyKey $op.ID}}: {
+		({{argList $op}}): {{methodReturnType $op}};
+{{- range $alt := $op.Accepts}}
+		({{acceptArgList $op $alt.MediaType}}): Promise<{{$alt.ReturnType}}>;
+{{- end}}
+	};
+{{- else}}
+	{{tsPropertyKey $op.ID}}: ({{argList $op}}) => {{methodReturnType $op}};
+{{- end}}
+{{- end}}
+{{- end}}
+{{- define "methods"}}
+{{- range $i, $op := .}}
+		{{tsPropertyKey $op.ID}}: ({{implArgList $op}}): {{implReturnType $op}} => {
+      return {{$op.Function}}(client, {{callArgs $op}}{{if $op.Config}}, config{{end}});
+    }{{if ne (add $i 1) (len $)}},{{end}}
+{{- end}}
+{{- end}}
+{{- define "functions"}}
+{{- range $op := .}}
+
+/**
+ * {{if $op.Description}}{{$op.Description}}{{else}}{{$op.Method | upper}} {{$op.DocPath}}{{end}}
+ *
+{{- if $op.Deprecated}}
+ * @deprecated
+{{- end}}
+ * @param client - The FetchClient instance to send the request with
+{{- range $param := $op.PathParams}}
+ * @param {{$param.Name}} - {{if $param.Description}}{{$param.Description}}{{else}}{{$param.Name}} parameter{{end}}{{if $param.Deprecated}} (deprecated){{end}}
+{{- end}}
+{{- if hasQueryParams $op}}
+ * @param query - Query parameters
+{{- end}}
+{{- if $op.HasBody}}
+ * @param body - Request body
+{{- end}}
+ * @param options - Request options (signal, timeout, operationId{{if $op.Download}}, onProgress{{end}}{{if $op.Accepts}}, accept{{end}})
+{{- if $op.Config}}
+ * @param config - Adapter configuration, as given to createAdapter
+{{- end}}
+ * @returns {{if $op.Stream}}Async iterable of {{$op.ResponseType}} items; abort ` + "`options.signal`" + ` to stop the stream{{else}}Promise resolving to {{returnType $op}}{{end}}
+ */
+{{- if $op.Accepts}}
+export function {{$op.Function}}(client: FetchClient, {{argList $op}}{{with $op.Config}}, config?: {{.}}{{end}}): {{methodReturnType $op}};
+{{- range $alt := $op.Accepts}}
+export function {{$op.Function}}(client: FetchClient, {{acceptArgList $op $alt.MediaType}}{{with $op.Config}}, config?: {{.}}{{end}}): Promise<{{$alt.ReturnType}}>;
+{{- end}}
+export function {{$op.Function}}(client: FetchClient, {{implArgList $op}}{{with $op.Config}}, config?: {{.}}{{end}}): Promise<any>;
+{{- end}}
+export function {{$op.Function}}(client: FetchClient, {{implArgList $op}}{{with $op.Config}}, config: {{.}} = {}{{end}}): {{implReturnType $op}} {
+{{- if $op.Download}}
+  const { onProgress, ...requestOptions } = options ?? {};
+  const finalOptions = { ...requestOptions, operationId: options?.operationId ?? {{tsStringLiteral $op.ID}}{{with $op.Decoding}}, responseType: {{tsStringLiteral .}} as const{{end}} };
+{{- else}}
+  const finalOptions = { ...options, operationId: options?.operationId ?? {{tsStringLiteral $op.ID}}{{with $op.Decoding}}, responseType: {{tsStringLiteral .}} as const{{end}} };
+{{- end}}
+{{- if $op.Server}}
+  const serverBase = expandServerUrl({{$op.Server}}, config.serverVariables);
+{{- else if usesServerBase $op}}
+  const serverBase = serverUrl(config.server, config.serverVariables);
 {{- end}}
-} {
-  return {
-{{- range $i, $op := .Ops}}
-		{{tsPropertyKey $op.ID}}: ({{argList $op}}): Promise<FetchResponse<{{responseType $op}}>> => {
-		const finalOptions = { ...options, operationId: options?.operationId ?? {{tsStringLiteral $op.ID}} };
 {{- if hasQueryParams $op}}
-      const queryString = query ? buildQueryParams(query) : '';
-      const url = ` + "`" + `{{$op.DisplayPath}}` + "`" + ` + (queryString ? '?' + queryString : '');
-			{{clientCall $op "url" "finalOptions"}}
+  const queryString = query ? buildQueryParams(query) : '';
+  const url = ` + "`" + `{{$op.DisplayPath}}` + "`" + ` + (queryString ? '?' + queryString : '');
+{{- with acceptCall $op "url" "finalOptions"}}
+  {{.}}
+{{- end}}
+  {{clientCall $op "url" "finalOptions"}}
 {{- else}}
-	{{clientCall $op (printf "%c%s%c" 96 $op.DisplayPath 96) "finalOptions"}}
+{{- with acceptCall $op (printf "%c%s%c" 96 $op.DisplayPath 96) "finalOptions"}}
+  {{.}}
+{{- end}}
+  {{clientCall $op (printf "%c%s%c" 96 $op.DisplayPath 96) "finalOptions"}}
+{{- end}}
+}
+{{- end}}
+{{- end}}
+{{- define "helpers"}}
+{{- if .ApiErrorResponseName}}
+
+/**
+ * A response for a declared error status. Check ` + "`ok`" + ` and narrow on ` + "`status`" + ` to get the typed error body.
+ */
+export type {{.ApiErrorResponseName}}<S extends number, E> = Omit<FetchResponse<never>, 'ok' | 'status' | 'error'> & {
+  ok: false;
+  status: S;
+  error: { message: string; body: E };
+};
 {{- end}}
-    }{{if ne (add $i 1) (len $.Ops)}},{{end}}
+{{- if .HasFormBody}}
+
+/**
+ * Serializes a form-urlencoded request body following the OpenAPI encoding rules for each property.
+ */
+{{if .ExportHelpers}}export {{end}}function encodeFormBody(body: object | undefined, encoding: Record<string, { contentType?: string; style?: string; explode?: boolean }> = {}): URLSearchParams {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(body ?? {})) {
+    if (value === undefined || value === null) continue;
+    const { contentType, style = 'form', explode = style === 'form' } = encoding[key] ?? {};
+    if (contentType?.includes('json')) {
+      params.append(key, JSON.stringify(value));
+    } else if (Array.isArray(value)) {
+      if (explode && style === 'form') {
+        for (const item of value) params.append(key, String(item));
+      } else {
+        params.append(key, value.map(String).join(style === 'spaceDelimited' ? ' ' : style === 'pipeDelimited' ? '|' : ','));
+      }
+    } else if (typeof value === 'object') {
+      const entries = Object.entries(value).filter(([, v]) => v !== undefined && v !== null);
+      if (style === 'deepObject') {
+        for (const [k, v] of entries) params.append(` + "`" + `${key}[${k}]` + "`" + `, String(v));
+      } else if (explode) {
+        for (const [k, v] of entries) params.append(k, String(v));
+      } else {
+        params.append(key, entries.flat().map(String).join(','));
+      }
+    } else {
+      params.append(key, String(value));
+    }
+  }
+  return params;
+}
 {{- end}}
+{{- if .HasMultipartBody}}
+
+/**
+ * Builds a multipart/form-data request body. Blobs and Files are sent as file parts, objects as JSON,
+ * arrays as repeated fields, and a part with an encoding ` + "`contentType`" + ` is sent as a Blob of that type.
+ */
+{{if .ExportHelpers}}export {{end}}function encodeMultipartBody(body: object | undefined, encoding: Record<string, { contentType?: string }> = {}): FormData {
+  const form = new FormData();
+  const appendPart = (key: string, value: unknown, contentType?: string): void => {
+    if (value === undefined || value === null) return;
+    if (value instanceof Blob) {
+      const part = contentType && !value.type ? new Blob([value], { type: contentType }) : value;
+      form.append(key, part, value instanceof File ? value.name : undefined);
+    } else if (contentType) {
+      form.append(key, new Blob([typeof value === 'string' ? value : JSON.stringify(value)], { type: contentType }));
+    } else if (typeof value === 'object') {
+      form.append(key, JSON.stringify(value));
+    } else {
+      form.append(key, String(value));
+    }
   };
+  for (const [key, value] of Object.entries(body ?? {})) {
+    const { contentType } = encoding[key] ?? {};
+    if (Array.isArray(value)) {
+      for (const item of value) appendPart(key, item, contentType);
+    } else {
+      appendPart(key, value, contentType);
+    }
+  }
+  return form;
 }
-{{range $i, $s := .SortedSchemas }}
-{{- $name := $s.Name }}
-{{- $schema := $s.Schema }}
+{{- end}}
+{{- if .ResponseTypeErrorName}}
 
-{{- if $schema.Description }}
-/** {{$schema.Description}} */
-{{- else }}
-/** {{$name}} schema */
-{{- end }}
+/**
+ * Thrown when the FetchClient returns a successful response whose body is not of the type the
+ * request's responseType option asked for, as a client that ignores responseType does.
+ */
+export class {{.ResponseTypeErrorName}} extends Error {
+  readonly responseType: string;
+  readonly response: FetchResponse<unknown>;
+
+  constructor(responseType: string, response: FetchResponse<unknown>) {
+    super('Expected the client to read the response body as ' + responseType);
+    this.name = '{{.ResponseTypeErrorName}}';
+    this.responseType = responseType;
+    this.response = response;
+  }
+}
+
+/**
+ * Checks that the body of a successful response was read as responseType asked, so a client that does
+ * not honour responseType fails with a {{.ResponseTypeErrorName}} instead of returning mistyped data.
+ */
+{{if .ExportHelpers}}export {{end}}async function checkResponseType<R extends FetchResponse<any>>(request: Promise<R>, responseType: 'text' | 'blob' | 'arrayBuffer'): Promise<R> {
+  const response = await request;
+  if (!response.ok) return response;
+  const data: unknown = response.data;
+  const decoded = responseType === 'text' ? typeof data === 'string' : responseType === 'blob' ? data instanceof Blob : data instanceof ArrayBuffer;
+  if (!decoded) throw new {{.ResponseTypeErrorName}}(responseType, response);
+  return response;
+}
+{{- end}}
+{{- if .HasXMLDocuments}}
+
+/**
+ * Parses the text body of a successful XML response into a Document.
+ */
+{{if .ExportHelpers}}export {{end}}function parseXmlResponse(response: FetchResponse<any>): FetchResponse<any> {
+  if (!response.ok || typeof response.data !== 'string') return response;
+  return { ...response, data: new DOMParser().parseFromString(response.data, 'application/xml') };
+}
+{{- end}}
+{{- if .ServerVariablesType}}
+
+/**
+ * A server the API is served from; {name} placeholders in url are replaced with variable values.
+ */
+export interface {{.ServerName}} {
+  url: string;
+  description?: string;
+  variables?: Readonly<Record<string, { default: string; enum?: readonly string[] }>>;
+}
+
+/**
+ * Values for server URL variables; variables left out use their defaults.
+ */
+export type {{.ServerVariablesName}} = {{.ServerVariablesType}};
+
+/**
+ * Expands the URL of server with the given variables, without a trailing slash.
+ */
+{{if .ExportHelpers}}export {{end}}function expandServerUrl(server: {{.ServerName}}, variables: {{.ServerVariablesName}} = {}): string {
+  const values = variables as Record<string, string | undefined>;
+  return server.url
+    .replace(/\{([^}]+)\}/g, (_, name: string) => values[name] ?? server.variables?.[name]?.default ?? '')
+    .replace(/\/+$/, '');
+}
+{{- end}}
+{{- if .Servers}}
+
+/**
+ * The servers declared by the API, in order of preference.
+ */
+export const servers: readonly {{.ServerName}}[] = [
+{{- range .Servers}}
+  {{.}},
+{{- end}}
+];
+
+/**
+ * Expands the URL of servers[index] with the given variables, without a trailing slash.
+ */
+export function serverUrl(index = 0, variables: {{.ServerVariablesName}} = {}): string {
+  const server = servers[index];
+  if (!server) throw new RangeError('Unknown server index ' + index);
+  return expandServerUrl(server, variables);
+}
+{{- end}}
+{{- if .SecuritySchemes}}
+
+/**
+ * A security scheme the API declares.
+ */
+export type {{.SecuritySchemeName}} =
+  | { type: 'apiKey'; name: string; in: 'header' | 'query' | 'cookie' }
+  | { type: 'http'; scheme: string; bearerFormat?: string }
+  | { type: 'oauth2' | 'openIdConnect' | 'mutualTLS' };
+
+/**
+ * The security schemes declared in components.securitySchemes.
+ */
+export const SecuritySchemes = {
+{{- range .SecuritySchemes}}
+  {{tsPropertyKey .Name}}: {{.Literal}},
+{{- end}}
+} as const;
+
+/**
+ * Security schemes that must all be satisfied, with the OAuth scopes required from each.
+ */
+export type {{.SecurityRequirementName}} = { readonly [S in keyof typeof SecuritySchemes]?: readonly string[] };
+
+/**
+ * The security requirements of each operation, any one of which grants access. Operations without
+ * requirements are public.
+ */
+export const SecurityRequirements: { readonly [operationId: string]: readonly {{.SecurityRequirementName}}[] } = {
+{{- range .Ops}}
+  {{tsPropertyKey .ID}}: {{.Security}},
+{{- end}}
+};
+
+/**
+ * A request for the credential of one security scheme an operation requires.
+ */
+export interface {{.AuthRequestName}} {
+  operationId: string;
+  scheme: keyof typeof SecuritySchemes;
+  scopes: readonly string[];
+}
+
+/**
+ * A bearer token or API key, or the username and password for HTTP basic authentication. A username
+ * and password do not satisfy any other scheme.
+ */
+export type {{.AuthCredentialName}} = string | { username: string; password: string };
+
+/**
+ * Applies the credentials of the first security requirement of operationId the auth hook can satisfy.
+ * Requests are sent without credentials when none can be.{{if .CookieSchemes}} Cookie API keys are left to
+ * the browser, which sends them when the client uses credentials: 'include'.{{end}}
+ */
+{{if .ExportHelpers}}export {{end}}async function authorize(auth: {{.AdapterConfigName}}['auth'], operationId: string, url: string, headersInit?: HeadersInit): Promise<{ url: string; headers: Headers }> {
+  const headers = new Headers(headersInit);
+  if (!auth) return { url, headers };
+  for (const requirement of SecurityRequirements[operationId] ?? []) {
+{{- if .CookieSchemes}}
+    const schemes = (Object.entries(requirement) as [keyof typeof SecuritySchemes, readonly string[]][]).filter(([scheme]) => {
+      const declared: {{.SecuritySchemeName}} = SecuritySchemes[scheme];
+      return !(declared.type === 'apiKey' && declared.in === 'cookie');
+    });
+{{- else}}
+    const schemes = Object.entries(requirement) as [keyof typeof SecuritySchemes, readonly string[]][];
+{{- end}}
+    if (schemes.length === 0) continue;
+    const credentials = await Promise.all(schemes.map(([scheme, scopes]) => auth({ operationId, scheme, scopes })));
+    if (credentials.some((credential) => credential === undefined)) continue;
+    const requestHeaders = new Headers(headers);
+    const query = new URLSearchParams();
+    if (!schemes.every(([scheme], i) => applyCredential(SecuritySchemes[scheme], credentials[i] as {{.AuthCredentialName}}, requestHeaders, query))) continue;
+    const queryString = query.toString();
+    return { url: queryString ? url + (url.includes('?') ? '&' : '?') + queryString : url, headers: requestHeaders };
+  }
+  return { url, headers };
+}
+
+/**
+ * Applies credential to headers or query the way scheme says, and reports whether it could: only HTTP
+ * basic takes a username and password, and mutual TLS happens below fetch, so a requirement naming it
+ * is never met here.
+ */
+function applyCredential(scheme: {{.SecuritySchemeName}}, credential: {{.AuthCredentialName}}, headers: Headers, query: URLSearchParams): boolean {
+  if (scheme.type === 'http' && scheme.scheme.toLowerCase() === 'basic') {
+    headers.set('Authorization', 'Basic ' + (typeof credential === 'string' ? credential : btoa(credential.username + ':' + credential.password)));
+    return true;
+  }
+  if (typeof credential !== 'string') return false;
+  switch (scheme.type) {
+    case 'apiKey':
+      if (scheme.in === 'query') query.set(scheme.name, credential);
+      else if (scheme.in === 'header') headers.set(scheme.name, credential);
+      return true;
+    case 'http':
+      headers.set('Authorization', scheme.scheme.charAt(0).toUpperCase() + scheme.scheme.slice(1) + ' ' + credential);
+      return true;
+    case 'oauth2':
+    case 'openIdConnect':
+      headers.set('Authorization', 'Bearer ' + credential);
+      return true;
+    default:
+      return false;
+  }
+}
+{{- end}}
+{{- if .AdapterConfigName}}
+
+/**
+ * Configures an adapter created by createAdapter.
+ */
+export interface {{.AdapterConfigName}} {
+{{- if .Servers}}
+  /** Index into servers of the server requests are sent to; defaults to the first. */
+  server?: number;
+{{- end}}
+{{- if .ServerVariablesType}}
+  /** Values for the URL variables of the {{if .Servers}}selected server{{end}}{{if and .Servers .HasOperationServers}} and of the {{end}}{{if .HasOperationServers}}operation-level servers{{end}}. */
+  serverVariables?: {{.ServerVariablesName}};
+{{- end}}
+{{- if .SecuritySchemes}}
+  /**
+   * Supplies credentials for operations that require them; return undefined when none is available.
+   * Public operations never call it.
+   */
+  auth?: (request: {{.AuthRequestName}}) => {{.AuthCredentialName}} | undefined | Promise<{{.AuthCredentialName}} | undefined>;
+{{- end}}
+}
+{{- end}}
+{{- if .HasResponseHeaders}}
+
+/**
+ * Exposes the headers an operation declares as typed properties of response.headers, parsed from
+ * their string values when read. A header missing from the response, or a number that does not
+ * parse, reads as undefined.
+ */
+{{if .ExportHelpers}}export {{end}}async function readResponseHeaders<R extends FetchResponse<any>>(request: Promise<R>, kinds: Record<string, string>): Promise<R> {
+  const response = await request;
+  for (const [name, kind] of Object.entries(kinds)) {
+    if (name in response.headers) continue;
+    Object.defineProperty(response.headers, name, {
+      get: () => parseHeader(response.headers.get(name), kind),
+      enumerable: true,
+      configurable: true,
+    });
+  }
+  return response;
+}
+
+function parseHeader(value: string | null, kind: string): unknown {
+  if (value === null) return undefined;
+  if (kind.endsWith('[]')) {
+    return value.split(',').map((item) => parseHeader(item.trim(), kind.slice(0, -2)));
+  }
+  switch (kind) {
+    case 'number': {
+      const parsed = Number(value);
+      return value.trim() === '' || Number.isNaN(parsed) ? undefined : parsed;
+    }
+    case 'boolean':
+      return value.trim().toLowerCase() === 'true';
+    default:
+      return value;
+  }
+}
+{{- end}}
+{{- if .FileDownloadName}}
+
+/**
+ * A downloaded file: the body as a Blob plus the filename from Content-Disposition, if any.
+ */
+export interface {{.FileDownloadName}} {
+  blob: Blob;
+  filename?: string;
+  contentType: string;
+  size: number;
+}
+
+/**
+ * Download progress; total is the Content-Length when the server sends one.
+ */
+export interface {{.DownloadProgressName}} {
+  loaded: number;
+  total?: number;
+}
+
+/**
+ * Reads a streamed binary response into a {{.FileDownloadName}}, reporting progress as chunks arrive. A
+ * successful response whose body the client did not return as a ReadableStream fails with a
+ * {{.ResponseTypeErrorName}}.
+ */
+{{if .ExportHelpers}}export {{end}}async function readDownload(request: Promise<FetchResponse<any>>, onProgress?: (progress: {{.DownloadProgressName}}) => void): Promise<FetchResponse<{{.FileDownloadName}}>> {
+  const response = await request;
+  if (!response.ok) return response;
+  if (!(response.data instanceof ReadableStream)) throw new {{.ResponseTypeErrorName}}('stream', response);
+  const contentType = response.headers.get('content-type') ?? 'application/octet-stream';
+  const length = Number(response.headers.get('content-length'));
+  const total = Number.isFinite(length) && length > 0 ? length : undefined;
+  const chunks: Uint8Array[] = [];
+  let loaded = 0;
+  const reader = (response.data as ReadableStream<Uint8Array>).getReader();
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) break;
+    chunks.push(value);
+    loaded += value.byteLength;
+    onProgress?.({ loaded, total });
+  }
+  const blob = new Blob(chunks as BlobPart[], { type: contentType });
+  const filename = contentDispositionFilename(response.headers.get('content-disposition'));
+  return { ...response, data: { blob, filename, contentType, size: blob.size } };
+}
+
+/**
+ * Extracts the filename from a Content-Disposition header, preferring the RFC 5987 filename* form.
+ */
+function contentDispositionFilename(header: string | null): string | undefined {
+  if (!header) return undefined;
+  const encoded = /filename\*\s*=\s*[^']*'[^']*'([^;]+)/i.exec(header);
+  if (encoded) {
+    try {
+      return decodeURIComponent(encoded[1].trim());
+    } catch {
+      // Fall back to the plain filename parameter.
+    }
+  }
+  const plain = /filename\s*=\s*("(?:[^"\\]|\\.)*"|[^;]+)/i.exec(header);
+  return plain ? plain[1].trim().replace(/^"(.*)"$/, '$1').replace(/\\(.)/g, '$1') : undefined;
+}
+{{- end}}
+{{- if or .HasEventStreams .HasNdjsonStreams}}
+
+/**
+ * Reads a streamed response body line by line, failing with a {{.ResponseTypeErrorName}} when the client did
+ * not return the body as a ReadableStream. Breaking out of the loop cancels the body; aborting the
+ * request signal ends the stream with an AbortError.
+ */
+async function* readStreamLines(request: Promise<FetchResponse<any>>): AsyncGenerator<string> {
+  const response = await request;
+  if (!response.ok) {
+    throw new Error(response.error?.message ?? ` + "`" + `Request failed with status ${response.status}` + "`" + `);
+  }
+  if (!(response.data instanceof ReadableStream)) throw new {{.ResponseTypeErrorName}}('stream', response);
+  const reader = (response.data as ReadableStream<Uint8Array>).pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = '';
+  try {
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) break;
+      buffer += value;
+      const lines = buffer.split(/\r\n|\n|\r(?!$)/);
+      buffer = lines.pop() ?? '';
+      yield* lines;
+    }
+    if (buffer) yield buffer.replace(/\r$/, '');
+  } finally {
+    reader.cancel().catch(() => undefined);
+  }
+}
+{{- end}}
+{{- if .HasEventStreams}}
+
+/**
+ * Parses a Server-Sent Events response into the data of each event.
+ */
+{{if .ExportHelpers}}export {{end}}async function* readEventStream<T>(request: Promise<FetchResponse<any>>, parseData: (data: string) => T): AsyncGenerator<T> {
+  let data: string[] = [];
+  for await (const line of readStreamLines(request)) {
+    if (line === '') {
+      if (data.length > 0) yield parseData(data.join('\n'));
+      data = [];
+    } else if (line.startsWith('data:')) {
+      data.push(line.slice(line.startsWith('data: ') ? 6 : 5));
+    } else if (line === 'data') {
+      data.push('');
+    }
+  }
+  if (data.length > 0) yield parseData(data.join('\n'));
+}
+{{- end}}
+{{- if .HasNdjsonStreams}}
+
+/**
+ * Parses a newline-delimited JSON response into one value per line.
+ */
+{{if .ExportHelpers}}export {{end}}async function* readNdjsonStream<T>(request: Promise<FetchResponse<any>>): AsyncGenerator<T> {
+  for await (const line of readStreamLines(request)) {
+    if (line.trim() !== '') yield JSON.parse(line) as T;
+  }
+}
+{{- end}}
+{{- end}}
+{{- define "model"}}
+{{- $name := .Name }}
+{{- $schema := .Schema }}
+/** {{if $schema.Deprecated}}@deprecated {{end}}{{if $schema.Description}}{{$schema.Description}}{{else}}{{$name}} schema{{end}} */
 {{- if isAlias $schema }}
-export type {{$name}} = {{ tsType $schema }};
+export type {{$name}} = {{ tsDefinition $schema }};
 {{- else }}
 export interface {{$name}} {
-{{- range $idx, $prop := $s.PropKeys }}
+{{- range $idx, $prop := .PropKeys }}
   {{- $def := index $schema.Properties $prop }}
-  {{- if $def.Description }}
+  {{- if and $def $def.Deprecated }}
+  /** @deprecated{{with $def.Description}} {{.}}{{end}} */
+  {{- else if $def.Description }}
   /** {{ $def.Description }} */
   {{- end }}
   {{- $isRequired := contains $schema.Required $prop }}
@@ -688,5 +2787,30 @@ export interface {{$name}} {
 {{- end }}
 }
 {{- end}}
-{{end}}
+{{- end}}
+{{- define "createAdapterDoc"}}
+/**
+ * Creates an API adapter with typed methods for all OpenAPI operations.
+ *
+ * @param client - The FetchClient instance to use for HTTP requests
+{{- if .AdapterConfigName}}
+ * @param config - Adapter configuration{{if .ServerVariablesType}}: the server to send requests to{{end}}{{if and .ServerVariablesType .SecuritySchemes}} and{{else if .SecuritySchemes}}:{{end}}{{if .SecuritySchemes}} the auth hook that supplies credentials{{end}}
+{{- end}}
+ * @returns An object with typed methods for each API operation
+ *
+ * @example
+ * ` + "```typescript" + `
+ * import { createAdapter } from './generated';
+ * import client from '@fgrzl/fetch';
+ *
+ * client.setBaseUrl('https://api.example.com');
+ * const api = createAdapter(client);
+ *
+ * const response = await api.getUsers();
+ * if (response.ok) {
+ *   console.log(response.data);
+ * }
+ * ` + "```" + `
+ */
+{{- end}}
 `
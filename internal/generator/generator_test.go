@@ -3,6 +3,7 @@ package generator_test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/fgrzl/fetch-gen/internal/generator"
@@ -15,6 +16,12 @@ import (
 func generateCodeFromFixture(t *testing.T, fixture string) string {
 	t.Helper()
 
+	return generateCodeFromFixtureWithOptions(t, fixture, generator.Options{})
+}
+
+func generateCodeFromFixtureWithOptions(t *testing.T, fixture string, opts generator.Options) string {
+	t.Helper()
+
 	fixturePath, err := filepath.Abs(filepath.Join("..", "..", "tests", "fixtures", fixture))
 	require.NoError(t, err)
 
@@ -24,7 +31,7 @@ func generateCodeFromFixture(t *testing.T, fixture string) string {
 	api, err := parser.ParseDocument(fixturePath, content)
 	require.NoError(t, err)
 
-	output, err := generator.Generate(api, "")
+	output, err := generator.Generate(api, opts)
 	require.NoError(t, err)
 
 	return string(output)
@@ -33,32 +40,60 @@ func generateCodeFromFixture(t *testing.T, fixture string) string {
 func generateCodeFromAPI(t *testing.T, api *apitypes.OpenAPI) string {
 	t.Helper()
 
-	output, err := generator.Generate(api, "")
+	return generateCodeFromAPIWithOptions(t, api, generator.Options{})
+}
+
+func generateCodeFromAPIWithOptions(t *testing.T, api *apitypes.OpenAPI, opts generator.Options) string {
+	t.Helper()
+
+	output, err := generator.Generate(api, opts)
 	require.NoError(t, err)
 
 	return string(output)
 }
 
+func generateFilesFromFixture(t *testing.T, fixture string, opts generator.Options) map[string]string {
+	t.Helper()
+
+	fixturePath, err := filepath.Abs(filepath.Join("..", "..", "tests", "fixtures", fixture))
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(fixturePath)
+	require.NoError(t, err)
+
+	api, err := parser.ParseDocument(fixturePath, content)
+	require.NoError(t, err)
+
+	files, err := generator.GenerateFiles(api, opts)
+	require.NoError(t, err)
+
+	output := map[string]string{}
+	for _, file := range files {
+		output[file.Path] = string(file.Content)
+	}
+	return output
+}
+
 func TestShouldReturnErrorGivenNilOpenAPIDocumentWhenGeneratingThenFail(t *testing.T) {
-	_, err := generator.Generate(nil, "")
+	_, err := generator.Generate(nil, generator.Options{})
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "openapi document is empty")
 }
 
 func TestShouldUseDefaultInstanceGivenBlankInstanceWhenGeneratingThenImportDefaultClient(t *testing.T) {
-	output, err := generator.Generate(&apitypes.OpenAPI{}, "")
+	output, err := generator.Generate(&apitypes.OpenAPI{}, generator.Options{})
 	require.NoError(t, err)
 	assert.Contains(t, string(output), "from '@fgrzl/fetch';")
 }
 
 func TestShouldUseCustomInstanceGivenProvidedInstanceWhenGeneratingThenImportCustomClient(t *testing.T) {
-	output, err := generator.Generate(&apitypes.OpenAPI{}, "./src/custom")
+	output, err := generator.Generate(&apitypes.OpenAPI{}, generator.Options{Instance: "./src/custom"})
 	require.NoError(t, err)
 	assert.Contains(t, string(output), "from './src/custom';")
 }
 
 func TestShouldExportCreateAdapterGivenEmptyOpenAPIDocumentWhenGeneratingThenEmitFactory(t *testing.T) {
-	output, err := generator.Generate(&apitypes.OpenAPI{}, "")
+	output, err := generator.Generate(&apitypes.OpenAPI{}, generator.Options{})
 	require.NoError(t, err)
 	assert.Contains(t, string(output), "export function createAdapter(client: FetchClient)")
 }
@@ -188,13 +223,13 @@ func TestShouldMakeOptionalRequestBodyGivenOptionalRequestBodyWhenGeneratingThen
 func TestShouldGenerateRequestBodyGivenComplexOpenAPIDocumentWhenGeneratingThenIncludeBodyArgument(t *testing.T) {
 	code := generateCodeFromFixture(t, "openapi-test.yaml")
 
-	assert.Contains(t, code, "createUser: (body: CreateUserRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<User>>")
+	assert.Contains(t, code, "createUser: (body: CreateUserRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<User> & { ok: true; status: 201 } | FetchResponse<AsyncResponse> & { ok: true; status: 202 } | ApiErrorResponse<400, ErrorResponse>>")
 }
 
 func TestShouldGeneratePathParametersGivenComplexOpenAPIDocumentWhenGeneratingThenIncludePathArguments(t *testing.T) {
 	code := generateCodeFromFixture(t, "openapi-test.yaml")
 
-	assert.Contains(t, code, "updateUser: (id: string, body: UpdateUserRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<User>>")
+	assert.Contains(t, code, "updateUser: (id: string, body: UpdateUserRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<User> & { ok: true } | ApiErrorResponse<404, unknown> | ApiErrorResponse<409, ErrorResponse>>")
 	assert.Contains(t, code, "getTeamMember: (org_id: string, team_id: string, member_id: string")
 	assert.Contains(t, code, "${encodeURIComponent(String(id))}")
 }
@@ -274,7 +309,7 @@ func TestShouldGenerateRawBinaryRequestGivenBinaryBodyWhenGeneratingThenForwardH
 	assert.NotContains(t, code, "client.put(`/objects/${encodeURIComponent(String(key))}/content`")
 }
 
-func TestShouldGenerateBlobResponseGivenBinaryContentWhenGeneratingThenTypeDownloadAsBlob(t *testing.T) {
+func TestShouldGenerateFileDownloadGivenBinaryContentWhenGeneratingThenTypeDownloadAsFile(t *testing.T) {
 	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
 		Paths: map[string]map[string]*apitypes.Operation{
 			"/objects/content": {
@@ -293,7 +328,23 @@ func TestShouldGenerateBlobResponseGivenBinaryContentWhenGeneratingThenTypeDownl
 		},
 	})
 
-	assert.Contains(t, code, "downloadObjectContent: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<Blob>>")
+	assert.Contains(t, code, "downloadObjectContent: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string; onProgress?: (progress: DownloadProgress) => void }): Promise<FetchResponse<FileDownload>>")
+	// onProgress is taken out of the options so it is not forwarded to the client.
+	assert.Contains(t, code, "const { onProgress, ...requestOptions } = options ?? {};")
+	assert.Contains(t, code, `const finalOptions = { ...requestOptions, operationId: options?.operationId ?? "downloadObjectContent", responseType: "stream" as const };`)
+	assert.Contains(t, code, "return readDownload(client.get(`/objects/content`, undefined, finalOptions), onProgress);")
+	assert.Contains(t, code, "export interface FileDownload {")
+	assert.Contains(t, code, "export interface DownloadProgress {")
+	assert.Contains(t, code, "async function readDownload(request: Promise<FetchResponse<any>>, onProgress?: (progress: DownloadProgress) => void): Promise<FetchResponse<FileDownload>> {")
+	assert.Contains(t, code, "  if (!response.ok) return response;\n  if (!(response.data instanceof ReadableStream)) throw new ResponseTypeError('stream', response);")
+	assert.Contains(t, code, "function contentDispositionFilename(header: string | null): string | undefined {")
+}
+
+func TestShouldOmitDownloadHelpersGivenNoBinaryResponsesWhenGeneratingThenKeepOutputMinimal(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.NotContains(t, code, "function readDownload")
+	assert.NotContains(t, code, "onProgress")
 }
 
 func TestShouldGenerateDeleteOperationGivenComplexOpenAPIDocumentWhenGeneratingThenIncludeQueryArguments(t *testing.T) {
@@ -317,20 +368,20 @@ func TestShouldGenerateUserSchemaGivenComplexOpenAPIDocumentWhenGeneratingThenEm
 func TestShouldGenerateBooleanResponsesGivenAuthApiWhenGeneratingThenReturnBoolean(t *testing.T) {
 	code := generateCodeFromFixture(t, "auth-api.yaml")
 
-	assert.Contains(t, code, "ssoCallback: (provider: string, query?: { code?: string; state?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean>>")
-	assert.Contains(t, code, "ssoLogin: (provider: string, query?: { email?: string; return_url?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean>>")
-	assert.Contains(t, code, "verifyEmail: (query?: { email?: string; token?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean>>")
+	assert.Contains(t, code, "ssoCallback: (provider: string, query?: { code?: string; state?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean> & { ok: true } | ApiErrorResponse<400, ProblemDetails>>")
+	assert.Contains(t, code, "ssoLogin: (provider: string, query?: { email?: string; return_url?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean> & { ok: true } | ApiErrorResponse<400, ProblemDetails>>")
+	assert.Contains(t, code, "verifyEmail: (query?: { email?: string; token?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean> & { ok: true } | ApiErrorResponse<400, ProblemDetails>>")
 	assert.Contains(t, code, "logout: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean>>")
 }
 
 func TestShouldGenerateTypedResponsesGivenAuthApiWhenGeneratingThenReturnResponseTypes(t *testing.T) {
 	code := generateCodeFromFixture(t, "auth-api.yaml")
 
-	assert.Contains(t, code, "getJWKS: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<JWKSResponse>>")
-	assert.Contains(t, code, "detectSSOProviders: (query?: { email?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<Array<string>>>")
-	assert.Contains(t, code, "getCurrentUser: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<UserIdentity>>")
-	assert.Contains(t, code, "getVerificationStatus: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<EmailVerificationStatus>>")
-	assert.Contains(t, code, "resendVerification: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<EmailVerificationStatus>>")
+	assert.Contains(t, code, "getJWKS: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<JWKSResponse> & { status: 200 } | FetchResponse<boolean> & { status: 204 }>")
+	assert.Contains(t, code, "detectSSOProviders: (query?: { email?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<Array<string>> & { ok: true } | ApiErrorResponse<400, ProblemDetails>>")
+	assert.Contains(t, code, "getCurrentUser: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<UserIdentity> & { ok: true } | ApiErrorResponse<401, Record<string, string>>>")
+	assert.Contains(t, code, "getVerificationStatus: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<EmailVerificationStatus> & { ok: true } | ApiErrorResponse<401, Record<string, string>>>")
+	assert.Contains(t, code, "resendVerification: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<EmailVerificationStatus> & { ok: true } | ApiErrorResponse<401, Record<string, string>>>")
 	assert.Contains(t, code, "export interface EmailVerificationStatus")
 	assert.Contains(t, code, "export interface JWKSResponse")
 	assert.Contains(t, code, "export interface ProblemDetails")
@@ -408,6 +459,7 @@ func TestShouldUseMultipartRequestBodyGivenNonJsonContentWhenGeneratingThenUseTy
 	})
 
 	assert.Contains(t, code, "uploadFile: (body: { fileName: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<boolean>>")
+	assert.Contains(t, code, "return client.request<boolean>(`/upload`, { method: \"POST\", body: encodeMultipartBody(body) }, finalOptions);")
 }
 
 func TestShouldUseDefaultResponseGivenDefaultTextResponseWhenGeneratingThenUseTypedSchema(t *testing.T) {
@@ -432,3 +484,1092 @@ func TestShouldUseDefaultResponseGivenDefaultTextResponseWhenGeneratingThenUseTy
 
 	assert.Contains(t, code, "getStatus: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<string>>")
 }
+
+func TestShouldGenerateTuplesGivenPrefixItemsWhenGeneratingThenEmitTupleTypes(t *testing.T) {
+	closed := false
+	two := 2
+	one := 1
+	numberSchema := &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"number"}}}
+	stringSchema := &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"string"}}}
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Coordinates": {
+					Type:        apitypes.SchemaType{Values: []string{"array"}},
+					PrefixItems: []*apitypes.Schema{numberSchema, numberSchema},
+					Items:       &apitypes.BooleanSchema{Boolean: &closed},
+					MinItems:    &two,
+				},
+				"LooseCoordinates": {
+					Type:        apitypes.SchemaType{Values: []string{"array"}},
+					PrefixItems: []*apitypes.Schema{numberSchema, numberSchema},
+					Items:       &apitypes.BooleanSchema{Boolean: &closed},
+				},
+				"TruncatedTriple": {
+					Type:        apitypes.SchemaType{Values: []string{"array"}},
+					PrefixItems: []*apitypes.Schema{stringSchema, numberSchema, stringSchema},
+					MinItems:    &one,
+					MaxItems:    &two,
+				},
+				"LabeledSeries": {
+					Type:        apitypes.SchemaType{Values: []string{"array"}},
+					PrefixItems: []*apitypes.Schema{stringSchema},
+					Items:       &apitypes.BooleanSchema{Schema: numberSchema},
+				},
+				"BoundedPair": {
+					PrefixItems: []*apitypes.Schema{stringSchema, numberSchema},
+					MinItems:    &one,
+					MaxItems:    &two,
+				},
+				"OpenPrefix": {
+					Type:        apitypes.SchemaType{Values: []string{"array"}},
+					PrefixItems: []*apitypes.Schema{stringSchema},
+				},
+				"Empty": {
+					Type:  apitypes.SchemaType{Values: []string{"array"}},
+					Items: &apitypes.BooleanSchema{Boolean: &closed},
+				},
+			},
+		},
+	})
+
+	assert.Contains(t, code, "export type Coordinates = [number, number];")
+	assert.Contains(t, code, "export type LooseCoordinates = [number?, number?];")
+	assert.Contains(t, code, "export type TruncatedTriple = [string, number?];")
+	assert.Contains(t, code, "export type LabeledSeries = [string?, ...number[]];")
+	assert.Contains(t, code, "export type BoundedPair = [string, number?];")
+	assert.Contains(t, code, "export type OpenPrefix = [string?, ...any[]];")
+	assert.Contains(t, code, "export type Empty = [];")
+}
+
+func TestShouldGenerateKeyedRecordsGivenPatternPropertiesAndPropertyNamesWhenGeneratingThenNarrowIndexSignatures(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-schema-edge-cases.yaml")
+
+	assert.Contains(t, code, "export type VendorExtensions = Record<`x-${string}`, string>;")
+	assert.Contains(t, code, `export type LocalizedLabels = Partial<Record<"en" | "fr", string>>;`)
+	// x- keys are also strings, so their values join the string-keyed Record instead of intersecting
+	// with it; { "x-rank": 1 } must stay assignable.
+	assert.Contains(t, code, "export type TaggedMetadata = { id: string } & Record<string, boolean | string | number>;")
+	assert.NotContains(t, code, "Record<`x-${string}`, number>")
+	assert.Contains(t, code, "export type ScopedExtensions = Record<`x-${string}`, string | number | boolean>;")
+	assert.Contains(t, code, "export type VendorSettings = Record<string, string | boolean>;")
+	assert.Contains(t, code, "export type OpenSettings = Record<string, number>;")
+}
+
+func TestShouldGenerateHelperTypesGivenDefsAnchorAndIdRefsWhenGeneratingThenEmitNamedTypes(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-schema-refs.yaml")
+
+	assert.Contains(t, code, "lines: Array<LineItem>;")
+	assert.Contains(t, code, "shipTo?: Address;")
+	assert.Contains(t, code, "billing?: PostalCode;")
+	assert.Contains(t, code, "export interface LineItem")
+	assert.Contains(t, code, "quantity?: Quantity;")
+	assert.Contains(t, code, "export type Quantity = number;")
+	assert.Contains(t, code, "export type PostalCode = string;")
+	assert.Contains(t, code, "listAddresses: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<Array<Address>>>")
+}
+
+func TestShouldApproximateConditionalSchemasGivenIfThenElseAndNotWhenGeneratingThenEmitUnionsExcludesAndWarnings(t *testing.T) {
+	stringType := apitypes.SchemaType{Values: []string{"string"}}
+	objectType := apitypes.SchemaType{Values: []string{"object"}}
+	api := &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Payment": {
+					Type:       objectType,
+					Properties: map[string]*apitypes.Schema{"kind": {Type: stringType}},
+					If:         &apitypes.Schema{Properties: map[string]*apitypes.Schema{"kind": {Enum: []any{"card"}}}},
+					Then:       &apitypes.Schema{Ref: "#/components/schemas/CardPayment"},
+					Else:       &apitypes.Schema{Ref: "#/components/schemas/BankPayment"},
+				},
+				"CardPayment": {Type: objectType, Properties: map[string]*apitypes.Schema{"card": {Type: stringType}}},
+				"BankPayment": {Type: objectType, Properties: map[string]*apitypes.Schema{"iban": {Type: stringType}}},
+				"OpenStatus": {
+					Enum: []any{"open", "closed", "archived"},
+					Not:  &apitypes.Schema{Enum: []any{"archived"}},
+				},
+				"PresentValue": {
+					Type: apitypes.SchemaType{Values: []string{"string", "null"}},
+					Not:  &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"null"}}},
+				},
+				"NotAnObject": {
+					Type: stringType,
+					Not:  &apitypes.Schema{Type: objectType, Properties: map[string]*apitypes.Schema{"id": {Type: stringType}}},
+				},
+			},
+		},
+	}
+
+	warnings := []string{}
+	output, err := generator.Generate(api, generator.Options{Warn: func(message string) {
+		warnings = append(warnings, message)
+	}})
+	require.NoError(t, err)
+	code := string(output)
+
+	assert.Contains(t, code, "export type Payment = { kind?: string } & (CardPayment | BankPayment);")
+	assert.Contains(t, code, `export type OpenStatus = Exclude<"open" | "closed" | "archived", "archived">;`)
+	assert.Contains(t, code, "export type PresentValue = Exclude<string | null, null>;")
+	assert.Contains(t, code, "export type NotAnObject = string;")
+	assert.Equal(t, []string{
+		`components.schemas["NotAnObject"]: not is only translated for literal enums and primitive types; constraint ignored`,
+		`components.schemas["Payment"]: if/then/else approximated as a union of the then and else branches`,
+	}, warnings)
+}
+
+func TestShouldRefineBaseObjectGivenRequiredOnlyConditionalBranchesWhenGeneratingThenRequireBranchKeys(t *testing.T) {
+	stringType := apitypes.SchemaType{Values: []string{"string"}}
+	objectType := apitypes.SchemaType{Values: []string{"object"}}
+	properties := map[string]*apitypes.Schema{"kind": {Type: stringType}, "card": {Type: stringType}, "iban": {Type: stringType}}
+	api := &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Pay": {
+					Type:       objectType,
+					Properties: properties,
+					If:         &apitypes.Schema{Properties: map[string]*apitypes.Schema{"kind": {Enum: []any{"card"}}}},
+					Then:       &apitypes.Schema{Required: []string{"card"}},
+					Else:       &apitypes.Schema{Required: []string{"iban"}},
+				},
+				"Loose": {
+					Type:       objectType,
+					Properties: properties,
+					If:         &apitypes.Schema{Properties: map[string]*apitypes.Schema{"kind": {Enum: []any{"card"}}}},
+					Then:       &apitypes.Schema{Required: []string{"card"}},
+					Else:       &apitypes.Schema{Description: "no constraint"},
+				},
+			},
+		},
+	}
+
+	warnings := []string{}
+	output, err := generator.Generate(api, generator.Options{Warn: func(message string) {
+		warnings = append(warnings, message)
+	}})
+	require.NoError(t, err)
+	code := string(output)
+
+	assert.Contains(t, code, "export type Pay = { card?: string; iban?: string; kind?: string } & ({ card: string } | { iban: string });")
+	assert.Contains(t, code, "export type Loose = { card?: string; iban?: string; kind?: string };")
+	assert.NotContains(t, code, "any |")
+	assert.Equal(t, []string{
+		`components.schemas["Loose"]: if/then/else branch without a type or required properties cannot be expressed in TypeScript; condition ignored`,
+		`components.schemas["Pay"]: if/then/else approximated as a union of the then and else branches`,
+	}, warnings)
+}
+
+func TestShouldHoistRecursiveInlineSchemasGivenPointerCyclesWhenGeneratingThenEmitNamedTypes(t *testing.T) {
+	stringType := apitypes.SchemaType{Values: []string{"string"}}
+	node := &apitypes.Schema{
+		Type:       apitypes.SchemaType{Values: []string{"object"}},
+		Properties: map[string]*apitypes.Schema{"label": {Type: stringType}},
+		Required:   []string{"label"},
+	}
+	node.Properties["children"] = &apitypes.Schema{
+		Type:  apitypes.SchemaType{Values: []string{"array"}},
+		Items: &apitypes.BooleanSchema{Schema: node},
+	}
+
+	left := &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"object"}}, Properties: map[string]*apitypes.Schema{}}
+	right := &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"object"}}, Properties: map[string]*apitypes.Schema{"left": left}}
+	left.Properties["right"] = right
+
+	api := &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Tree": {
+					Type:       apitypes.SchemaType{Values: []string{"object"}},
+					Properties: map[string]*apitypes.Schema{"root": node},
+				},
+				"Self": {
+					Type:       apitypes.SchemaType{Values: []string{"object"}},
+					Properties: map[string]*apitypes.Schema{},
+				},
+			},
+		},
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/graph": {
+				"get": {
+					OperationID: "getGraph",
+					Responses: map[string]*apitypes.Response{
+						"200": {Content: map[string]apitypes.MediaType{"application/json": {Schema: left}}},
+					},
+				},
+			},
+		},
+	}
+	self := api.Components.Schemas["Self"]
+	self.Properties["parent"] = self
+
+	code := generateCodeFromAPI(t, api)
+
+	assert.Contains(t, code, "root?: TreeNode;")
+	assert.Contains(t, code, "export interface TreeNode {")
+	assert.Contains(t, code, "children?: Array<TreeNode>;")
+	assert.Contains(t, code, "parent?: Self;")
+	assert.Contains(t, code, "getGraph: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<GetGraphResponseNode>>")
+	assert.Contains(t, code, "export interface GetGraphResponseNode {")
+	assert.Contains(t, code, "right?: { left?: GetGraphResponseNode };")
+}
+
+func TestShouldTypeErrorResponsesGivenErrorStatusCodesWhenGeneratingThenReturnDiscriminatedUnion(t *testing.T) {
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/orders/{id}": {
+				"get": {
+					OperationID: "getOrder",
+					Parameters: []*apitypes.Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"string"}}}},
+					},
+					Responses: map[string]*apitypes.Response{
+						"200": {Content: map[string]apitypes.MediaType{
+							"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/Order"}},
+						}},
+						"404": {Description: "Not Found"},
+						"422": {Content: map[string]apitypes.MediaType{
+							"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/ValidationError"}},
+						}},
+						"default": {Content: map[string]apitypes.MediaType{
+							"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/Problem"}},
+						}},
+					},
+				},
+			},
+		},
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Order":           {Type: apitypes.SchemaType{Values: []string{"object"}}},
+				"ValidationError": {Type: apitypes.SchemaType{Values: []string{"object"}}},
+				"Problem":         {Type: apitypes.SchemaType{Values: []string{"object"}}},
+			},
+		},
+	})
+
+	returnType := "Promise<FetchResponse<Order> & { ok: true } | ApiErrorResponse<404, unknown> | ApiErrorResponse<422, ValidationError> | ApiErrorResponse<number, Problem>>"
+	assert.Contains(t, code, "export type ApiErrorResponse<S extends number, E> = Omit<FetchResponse<never>, 'ok' | 'status' | 'error'> & {")
+	assert.Contains(t, code, "getOrder: (id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): "+returnType)
+	assert.Contains(t, code, "return client.get<Order>(`/orders/${encodeURIComponent(String(id))}`, undefined, finalOptions) as "+returnType+";")
+}
+
+func TestShouldKeepHelperNameGivenComponentNamedErrorResponseWhenGeneratingThenUseBothNames(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.Contains(t, code, "export type ApiErrorResponse<S extends number, E>")
+	assert.Contains(t, code, "export interface ErrorResponse {")
+	assert.Contains(t, code, "ApiErrorResponse<400, ErrorResponse>")
+}
+
+func TestShouldRenameErrorHelperGivenComponentNamedApiErrorResponseWhenGeneratingThenAvoidCollision(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(strings.Join([]string{
+		"paths:",
+		"  /orders:",
+		"    get:",
+		"      operationId: listOrders",
+		"      responses:",
+		"        \"204\":",
+		"          description: ok",
+		"        \"400\":",
+		"          description: bad request",
+		"          content:",
+		"            application/json:",
+		"              schema:",
+		"                $ref: '#/components/schemas/ApiErrorResponse'",
+		"components:",
+		"  schemas:",
+		"    ApiErrorResponse:",
+		"      type: object",
+		"      properties:",
+		"        message:",
+		"          type: string",
+	}, "\n")))
+	require.NoError(t, err)
+
+	code := generateCodeFromAPI(t, api)
+
+	assert.Contains(t, code, "export type ApiErrorResponse2<S extends number, E>")
+	assert.Contains(t, code, "ApiErrorResponse2<400, ApiErrorResponse>")
+}
+
+func TestShouldKeepPlainResponseGivenNoErrorStatusCodesWhenGeneratingThenOmitErrorHelper(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-anyof.yaml")
+
+	assert.NotContains(t, code, "export type ApiErrorResponse")
+}
+
+func TestShouldUnionSuccessResponsesGivenMultipleSuccessCodesWhenGeneratingThenDiscriminateOnStatus(t *testing.T) {
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/jobs": {
+				"post": {
+					OperationID: "submitJob",
+					Responses: map[string]*apitypes.Response{
+						"200": {Content: map[string]apitypes.MediaType{
+							"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/User"}},
+						}},
+						"202": {Content: map[string]apitypes.MediaType{
+							"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/JobAccepted"}},
+						}},
+						"204": {Description: "No Content"},
+					},
+				},
+			},
+		},
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"User":        {Type: apitypes.SchemaType{Values: []string{"object"}}},
+				"JobAccepted": {Type: apitypes.SchemaType{Values: []string{"object"}}},
+			},
+		},
+	})
+
+	returnType := "Promise<FetchResponse<User> & { status: 200 } | FetchResponse<JobAccepted> & { status: 202 } | FetchResponse<boolean> & { status: 204 }>"
+	assert.Contains(t, code, "submitJob: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): "+returnType)
+	assert.Contains(t, code, "return client.post<User | JobAccepted | boolean>(`/jobs`, undefined, undefined, finalOptions) as "+returnType+";")
+}
+
+func TestShouldPreferJsonSuffixMediaTypesGivenVendorAndProblemJsonWhenGeneratingThenUseJsonSchemas(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-content-negotiation.yaml")
+
+	assert.Contains(t, code, "getReport: (id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<Report> & { ok: true } | ApiErrorResponse<404, Problem>>;")
+	assert.Contains(t, code, "patchReport: (id: string, body?: ReportPatch, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<Report>>;")
+}
+
+func TestShouldFollowMediaTypePreferenceGivenCustomMediaTypesWhenGeneratingThenUsePreferredSchemas(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "openapi-content-negotiation.yaml", generator.Options{MediaTypes: []string{"text/*", "*/*"}})
+
+	assert.Contains(t, code, "getReport: (id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<string> & { ok: true } | ApiErrorResponse<404, Problem>>;")
+	assert.Contains(t, code, "patchReport: (id: string, body?: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<Report>>;")
+}
+
+func TestShouldReturnErrorGivenInvalidMediaTypePreferenceWhenGeneratingThenFail(t *testing.T) {
+	_, err := generator.Generate(&apitypes.OpenAPI{}, generator.Options{MediaTypes: []string{"json"}})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `invalid media type preference "json"`)
+}
+
+func TestShouldGenerateAcceptOverloadsGivenAlternativeResponseMediaTypesWhenGeneratingThenSetAcceptHeader(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "openapi-content-negotiation.yaml", generator.Options{AcceptOverloads: true})
+
+	assert.Contains(t, code, "(id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<Report> & { ok: true } | ApiErrorResponse<404, Problem>>;")
+	assert.Contains(t, code, `(id: string, options: { signal?: AbortSignal; timeout?: number; operationId?: string; accept: "text/csv" }): Promise<FetchResponse<string> & { ok: true } | ApiErrorResponse<404, Problem>>;`)
+	assert.Contains(t, code, `getReport: (id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string; accept?: "text/csv" }): Promise<any> => {`)
+	assert.Contains(t, code, `if (options?.accept === "text/csv") {`)
+	assert.Contains(t, code, `requestHeaders.set('Accept', "text/csv");`)
+	assert.Contains(t, code, "return checkResponseType(client.request<any>(`/reports/${encodeURIComponent(String(id))}`, { method: \"GET\", headers: requestHeaders }, { ...finalOptions, responseType: \"text\" as const }), \"text\");")
+	assert.Contains(t, code, "patchReport: (id: string, body?: ReportPatch, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<Report>>;")
+}
+
+func TestShouldEncodeFormBodyGivenUrlEncodedContentWhenGeneratingThenSendUrlSearchParams(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-request-bodies.yaml")
+
+	assert.Contains(t, code, "function encodeFormBody(body: object | undefined, encoding: Record<string, { contentType?: string; style?: string; explode?: boolean }> = {}): URLSearchParams {")
+	assert.Contains(t, code, "issueToken: (body: TokenRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<TokenResponse>>;")
+	assert.Contains(t, code, "return client.request<TokenResponse>(`/oauth/token`, { method: \"POST\", headers: { 'Content-Type': 'application/x-www-form-urlencoded' }, body: encodeFormBody(body, { claims: { contentType: \"application/json\" }, scope: { style: \"spaceDelimited\", explode: false } }) }, finalOptions);")
+	assert.Contains(t, code, "body: encodeFormBody(body) }, finalOptions);")
+}
+
+func TestShouldOmitFormEncoderGivenJsonBodiesWhenGeneratingThenSkipHelper(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.NotContains(t, code, "function encodeFormBody")
+}
+
+func TestShouldBuildFormDataGivenMultipartContentWhenGeneratingThenSendTypedParts(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-request-bodies.yaml")
+
+	assert.Contains(t, code, "function encodeMultipartBody(body: object | undefined, encoding: Record<string, { contentType?: string }> = {}): FormData {")
+	assert.Contains(t, code, "for (const item of value) appendPart(key, item, contentType);")
+	assert.Contains(t, code, "uploadDocuments: (body: { files: Array<Blob>; metadata?: DocumentMetadata; tags?: Array<string>; thumbnail?: Blob }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<any>>;")
+	assert.Contains(t, code, "return client.request<any>(`/documents`, { method: \"POST\", body: encodeMultipartBody(body, { metadata: { contentType: \"application/json\" } }) }, finalOptions);")
+}
+
+func TestShouldRequestDecodingGivenNonJsonResponsesWhenGeneratingThenPassResponseType(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-response-decoding.yaml")
+
+	assert.Contains(t, code, "exportOrdersCsv: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<string>> => {")
+	assert.Contains(t, code, `const finalOptions = { ...options, operationId: options?.operationId ?? "exportOrdersCsv", responseType: "text" as const };`)
+	// The client reads responseType from the request options it is given, and the body it returns is
+	// checked, so a client that ignores the option fails with a ResponseTypeError.
+	assert.Contains(t, code, "return checkResponseType(client.get(`/exports/orders.csv`, undefined, finalOptions), \"text\");")
+	assert.Contains(t, code, "return checkResponseType(client.get(`/snapshots/latest`, undefined, finalOptions), \"arrayBuffer\");")
+	assert.Contains(t, code, "export class ResponseTypeError extends Error {")
+	assert.Contains(t, code, "const decoded = responseType === 'text' ? typeof data === 'string' : responseType === 'blob' ? data instanceof Blob : data instanceof ArrayBuffer;\n  if (!decoded) throw new ResponseTypeError(responseType, response);")
+	assert.Contains(t, code, "getAvatar: (id: string, options?: { signal?: AbortSignal; timeout?: number; operationId?: string; onProgress?: (progress: DownloadProgress) => void }): Promise<FetchResponse<FileDownload>> => {")
+	assert.Contains(t, code, `const finalOptions = { ...requestOptions, operationId: options?.operationId ?? "getAvatar", responseType: "stream" as const };`)
+	assert.Contains(t, code, "getSnapshot: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<ArrayBuffer>> => {")
+	assert.Contains(t, code, `const finalOptions = { ...options, operationId: options?.operationId ?? "getSnapshot", responseType: "arrayBuffer" as const };`)
+}
+
+func TestShouldTypeXmlResponsesGivenStructuredAndStringSchemasWhenGeneratingThenReturnDocumentOrString(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-response-decoding.yaml")
+
+	assert.Contains(t, code, "function parseXmlResponse(response: FetchResponse<any>): FetchResponse<any> {")
+	assert.Contains(t, code, "return checkResponseType(client.get<string>(`/feeds/catalog`, undefined, finalOptions), \"text\").then(parseXmlResponse) as Promise<FetchResponse<Document>>;")
+	assert.Contains(t, code, "getSitemap: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<string>> => {")
+	assert.Contains(t, code, `const finalOptions = { ...options, operationId: options?.operationId ?? "getSitemap", responseType: "text" as const };`)
+}
+
+func TestShouldKeepDefaultDecodingGivenJsonResponsesWhenGeneratingThenOmitResponseType(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.NotContains(t, code, "responseType:")
+	assert.NotContains(t, code, "ResponseTypeError")
+	assert.NotContains(t, code, "function parseXmlResponse")
+}
+
+func TestShouldReturnAsyncIterableGivenEventStreamAndNdjsonResponsesWhenGeneratingThenStreamTypedItems(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-streaming.yaml")
+
+	assert.Contains(t, code, "async function* readStreamLines(request: Promise<FetchResponse<any>>): AsyncGenerator<string> {")
+	assert.Contains(t, code, "async function* readEventStream<T>(request: Promise<FetchResponse<any>>, parseData: (data: string) => T): AsyncGenerator<T> {")
+	assert.Contains(t, code, "async function* readNdjsonStream<T>(request: Promise<FetchResponse<any>>): AsyncGenerator<T> {")
+	assert.Contains(t, code, "streamNotifications: (query?: { topic?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => AsyncIterable<Notification>;")
+	assert.Contains(t, code, "return readEventStream<Notification>(client.get(url, undefined, finalOptions), JSON.parse);")
+	assert.Contains(t, code, "streamCompletion: (body: CompletionRequest, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => AsyncIterable<CompletionChunk>;")
+	assert.Contains(t, code, "return readNdjsonStream<CompletionChunk>(client.post(`/completions`, body, undefined, finalOptions));")
+	assert.Contains(t, code, `const finalOptions = { ...options, operationId: options?.operationId ?? "streamCompletion", responseType: "stream" as const };`)
+}
+
+func TestShouldThrowResponseTypeErrorGivenStreamResponsesWhenGeneratingThenCheckBodyBeforeReading(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-streaming.yaml")
+
+	assert.Contains(t, code, "export class ResponseTypeError extends Error {")
+	assert.Contains(t, code, "  if (!response.ok) {\n    throw new Error(")
+	assert.Contains(t, code, "  if (!(response.data instanceof ReadableStream)) throw new ResponseTypeError('stream', response);\n  const reader = (response.data as ReadableStream<Uint8Array>)")
+}
+
+func TestShouldYieldTextGivenEventStreamWithoutItemSchemaWhenGeneratingThenSkipJsonParsing(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-streaming.yaml")
+
+	assert.Contains(t, code, "tailLogs: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => AsyncIterable<string>;")
+	assert.Contains(t, code, "return readEventStream<string>(client.get(`/logs/tail`, undefined, finalOptions), String);")
+}
+
+func TestShouldTypeResponseHeadersGivenDeclaredHeadersWhenGeneratingThenExposeParsedHeaders(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-response-headers.yaml")
+
+	assert.Contains(t, code, `listOrders: (options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<FetchResponse<Array<Order>> & { headers: Headers & { etag?: string; "x-has-more"?: boolean; "x-ratelimit-remaining"?: number; "x-total-count": number | undefined } }>;`)
+	assert.Contains(t, code, "return readResponseHeaders(client.get<Array<Order>>(`/orders`, undefined, finalOptions), { etag: \"string\", \"x-has-more\": \"boolean\", \"x-ratelimit-remaining\": \"number\", \"x-total-count\": \"number\" }) as Promise<")
+	assert.Contains(t, code, `FetchResponse<Order> & { ok: true; status: 201; headers: Headers & { location: string | undefined } } | FetchResponse<boolean> & { ok: true; status: 202; headers: Headers & { "retry-after"?: number } } | ApiErrorResponse<400, unknown>`)
+	assert.Contains(t, code, `FetchResponse<Order> & { ok: true; headers: Headers & { "x-warehouse-ids"?: Array<number> } } | ApiErrorResponse<404, unknown>`)
+	assert.Contains(t, code, `{ "x-warehouse-ids": "number[]" }`)
+	assert.Contains(t, code, "async function readResponseHeaders<R extends FetchResponse<any>>(request: Promise<R>, kinds: Record<string, string>): Promise<R> {")
+	assert.NotContains(t, code, `"content-type"`)
+}
+
+func TestShouldAllowUndefinedGivenMissingRequiredResponseHeaderWhenGeneratingThenTypeAndParseAsUndefined(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-response-headers.yaml")
+
+	// x-total-count is required, but a response without it still reads as undefined.
+	assert.Contains(t, code, `"x-total-count": number | undefined`)
+	assert.NotContains(t, code, `"x-total-count": number }`)
+	assert.Contains(t, code, "function parseHeader(value: string | null, kind: string): unknown {\n  if (value === null) return undefined;")
+}
+
+func TestShouldOmitHeaderHelpersGivenNoResponseHeadersWhenGeneratingThenKeepPlainResponses(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.NotContains(t, code, "readResponseHeaders")
+	assert.NotContains(t, code, "headers: Headers &")
+}
+
+func TestShouldAuthorizeSecuredOperationsGivenSecuritySchemesWhenGeneratingThenEmitRequirementsAndAuthHook(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-security.yaml")
+
+	assert.Contains(t, code, `bearerAuth: { type: "http", scheme: "bearer", bearerFormat: "JWT" },`)
+	assert.Contains(t, code, `apiKey: { type: "apiKey", name: "X-API-Key", in: "header" },`)
+	assert.Contains(t, code, `createAccount: [{ oauth: ["accounts:write"] }, { apiKey: [] }],`)
+	assert.Contains(t, code, "listAccounts: [{ bearerAuth: [] }],")
+	assert.Contains(t, code, "getHealth: [],")
+	assert.Contains(t, code, "getReport: [{}, { basicAuth: [] }],")
+	assert.Contains(t, code, "export function createAdapter(client: FetchClient, config: AdapterConfig = {}): {")
+	assert.Contains(t, code, "auth?: (request: AuthRequest) => AuthCredential | undefined | Promise<AuthCredential | undefined>;")
+	assert.Contains(t, code, "return (config.auth ? authorize(config.auth, \"listAccounts\", url).then(({ url, headers: requestHeaders }) => client.request<Array<Account>>(url, { method: \"GET\", headers: requestHeaders }, finalOptions)) : client.get(url, undefined, finalOptions));")
+	assert.Contains(t, code, "authorize(config.auth, \"createAccount\", `/accounts`, { 'Content-Type': \"application/json\" })")
+	assert.Contains(t, code, "return client.get(`/health`, undefined, finalOptions);")
+}
+
+func TestShouldLeaveCookieToBrowserGivenCookieApiKeySchemeWhenGeneratingThenSkipCookieHeaderAndWarn(t *testing.T) {
+	warnings := []string{}
+	output, err := generator.Generate(&apitypes.OpenAPI{
+		Security: []apitypes.SecurityRequirement{{"session": {}}},
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/me": {
+				"get": {OperationID: "getMe", Responses: map[string]*apitypes.Response{"200": {Description: "ok"}}},
+			},
+		},
+		Components: apitypes.Components{
+			SecuritySchemes: map[string]*apitypes.SecurityScheme{
+				"session": {Type: "apiKey", Name: "sid", In: "cookie"},
+			},
+		},
+	}, generator.Options{Warn: func(message string) {
+		warnings = append(warnings, message)
+	}})
+	require.NoError(t, err)
+	code := string(output)
+
+	assert.NotContains(t, code, "'Cookie'")
+	assert.Contains(t, code, "return !(declared.type === 'apiKey' && declared.in === 'cookie');")
+	assert.Contains(t, code, "else if (scheme.in === 'header') headers.set(scheme.name, credential);")
+	assert.Equal(t, []string{`security scheme "session": fetch cannot set the Cookie header, so the auth hook is not asked for it; send the cookie with credentials: 'include'`}, warnings)
+}
+
+func TestShouldSkipUnmetRequirementGivenCredentialTheSchemeCannotTakeWhenGeneratingThenTryNextRequirement(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-security.yaml")
+
+	assert.Contains(t, code, "function applyCredential(scheme: SecurityScheme, credential: AuthCredential, headers: Headers, query: URLSearchParams): boolean {")
+	// Only HTTP basic takes a username and password; bearer, API key and OAuth credentials must be strings.
+	assert.Contains(t, code, "  if (typeof credential !== 'string') return false;\n")
+	// mutualTLS cannot be applied through fetch, so its requirements are never met.
+	assert.Contains(t, code, "    default:\n      return false;\n")
+	// Credentials are applied to a copy of the headers, so a requirement that fails halfway leaves nothing behind.
+	assert.Contains(t, code, "if (!schemes.every(([scheme], i) => applyCredential(SecuritySchemes[scheme], credentials[i] as AuthCredential, requestHeaders, query))) continue;")
+}
+
+func TestShouldOmitAuthHookGivenNoSecuritySchemesWhenGeneratingThenKeepSingleArgumentFactory(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-test.yaml")
+
+	assert.Contains(t, code, "export function createAdapter(client: FetchClient): {")
+	assert.NotContains(t, code, "SecurityRequirements")
+	assert.NotContains(t, code, "authorize(")
+}
+
+func TestShouldSelectServerAtRuntimeGivenTemplatedServersWhenGeneratingThenExportServersAndExpandVariables(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-servers.yaml")
+
+	assert.Contains(t, code, `{ url: "https://{region}.api.example.com/{version}/", description: "Regional production", variables: { region: { default: "us", enum: ["us", "eu"] }, version: { default: "v1" } } },`)
+	assert.Contains(t, code, `export type ServerVariables = { region?: "eu" | "us"; version?: string };`)
+	assert.Contains(t, code, "export function serverUrl(index = 0, variables: ServerVariables = {}): string {")
+	assert.Contains(t, code, "export function createAdapter(client: FetchClient, config: AdapterConfig = {}): {")
+	assert.Contains(t, code, "const serverBase = serverUrl(config.server, config.serverVariables);")
+	assert.Contains(t, code, "client.get(`${serverBase}/users/${encodeURIComponent(String(id))}`, undefined, finalOptions)")
+	assert.Contains(t, code, "   * GET /users/${encodeURIComponent(String(id))}\n")
+	assert.NotContains(t, code, "auth?:")
+}
+
+func TestShouldInlineServerURLGivenSinglePlainServerWhenGeneratingThenOmitServerSelection(t *testing.T) {
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Servers: []apitypes.Server{{URL: "https://api.example.com/v2/"}},
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/users": {
+				"get": {OperationID: "listUsers", Responses: map[string]*apitypes.Response{"200": {Description: "ok"}}},
+			},
+		},
+	})
+
+	assert.Contains(t, code, "client.get(`https://api.example.com/v2/users`, undefined, finalOptions)")
+	assert.NotContains(t, code, "serverUrl(")
+	assert.Contains(t, code, "export function createAdapter(client: FetchClient): {")
+}
+
+func TestShouldOverrideServerGivenOperationAndPathServersWhenGeneratingThenUseOverrideURL(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-servers.yaml")
+
+	assert.Contains(t, code, `const serverBase = expandServerUrl({ url: "https://uploads-{region}.example.com/", variables: { region: { default: "us", enum: ["us", "eu"] } } }, config.serverVariables);`)
+	assert.Contains(t, code, "return client.request<any>(`${serverBase}/uploads`, { method: \"POST\", headers, body }, finalOptions);")
+	assert.Contains(t, code, " * POST https://uploads-{region}.example.com/uploads\n")
+	assert.Contains(t, code, "return uploadFile(client, body, headers, options, config);")
+	assert.Contains(t, code, "return client.get(`/reporting/reports/${encodeURIComponent(String(id))}`, undefined, finalOptions);")
+	assert.Contains(t, code, "client.get(`${serverBase}/users/${encodeURIComponent(String(id))}`, undefined, finalOptions)")
+}
+
+func TestShouldExpandOperationServerAtRuntimeGivenOnlyTemplatedOperationServersWhenGeneratingThenOmitServerTable(t *testing.T) {
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/uploads": {
+				"post": {
+					OperationID: "uploadFile",
+					Servers: []apitypes.Server{{
+						URL:       "https://uploads-{region}.example.com",
+						Variables: map[string]*apitypes.ServerVariable{"region": {Default: "us"}},
+					}},
+					Responses: map[string]*apitypes.Response{"204": {Description: "ok"}},
+				},
+			},
+		},
+	})
+
+	assert.Contains(t, code, "export type ServerVariables = { region?: string };")
+	assert.Contains(t, code, "function expandServerUrl(server: Server, variables: ServerVariables = {}): string {")
+	assert.Contains(t, code, "  /** Values for the URL variables of the operation-level servers. */\n  serverVariables?: ServerVariables;")
+	assert.NotContains(t, code, "server?: number;")
+	assert.NotContains(t, code, "export const servers")
+	assert.Contains(t, code, "export function createAdapter(client: FetchClient, config: AdapterConfig = {}): {")
+}
+
+func TestShouldGenerateParametersGivenPathLevelParametersWhenGeneratingThenRequireThemOnEveryOperation(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(strings.Join([]string{
+		"paths:",
+		"  /items:",
+		"    summary: Items",
+		"    parameters:",
+		"      - name: tenant",
+		"        in: query",
+		"        required: true",
+		"        schema:",
+		"          type: string",
+		"    get:",
+		"      operationId: listItems",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+	}, "\n")))
+	require.NoError(t, err)
+
+	code := generateCodeFromAPI(t, api)
+
+	assert.Contains(t, code, "export function listItems(client: FetchClient, query: { tenant: string }, ")
+}
+
+func TestShouldNestMethodsGivenGroupByTagWhenGeneratingThenEmitSubAdaptersPerFirstTag(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "auth-api.yaml", generator.Options{GroupByTag: true})
+
+	assert.Contains(t, code, "  authentication: {\n  /**\n   * Get JSON Web Key Set")
+	assert.Contains(t, code, "  emailVerification: {\n")
+	assert.Contains(t, code, "  sso: {\n")
+	assert.Contains(t, code, "\tdetectSSOProviders: (query?: { email?: string }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }) => Promise<")
+	assert.Contains(t, code, "    },\n    emailVerification: {\n")
+}
+
+func TestShouldPlaceUntaggedOperationsGivenGroupByTagWhenGeneratingThenUseDefaultGroup(t *testing.T) {
+	code := generateCodeFromAPIWithOptions(t, &apitypes.OpenAPI{
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/users/{id}": {
+				"get": {
+					OperationID: "getUser",
+					Tags:        []string{"Users", "Admin"},
+					Parameters:  []*apitypes.Parameter{{Name: "id", In: "path", Required: true, Schema: &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"string"}}}}},
+					Responses:   map[string]*apitypes.Response{"200": {Description: "ok"}},
+				},
+			},
+			"/health": {
+				"get": {OperationID: "getHealth", Responses: map[string]*apitypes.Response{"200": {Description: "ok"}}},
+			},
+		},
+	}, generator.Options{GroupByTag: true})
+
+	assert.Contains(t, code, "  default: {\n  /**\n   * GET /health")
+	assert.Contains(t, code, "  users: {\n  /**")
+	assert.Contains(t, code, "\tgetUser: (id: string, options?:")
+	assert.NotContains(t, code, "  admin: {")
+}
+
+func TestShouldSplitModulesGivenTaggedOperationsWhenGeneratingFilesThenEmitModelsAdaptersAndIndex(t *testing.T) {
+	files := generateFilesFromFixture(t, "auth-api.yaml", generator.Options{})
+
+	assert.Contains(t, files, "models/JWKSResponse.ts")
+	assert.Contains(t, files, "adapters/authentication.ts")
+	assert.Contains(t, files, "adapters/emailVerification.ts")
+	assert.Contains(t, files, "adapters/sso.ts")
+	assert.Contains(t, files, "helpers.ts")
+	assert.Contains(t, files, "index.ts")
+
+	assert.Contains(t, files["models/JWKSResponse.ts"], "import type { JWKResponse } from './JWKResponse';\n\n/** JWKSResponse schema */\nexport interface JWKSResponse {")
+	assert.Contains(t, files["adapters/authentication.ts"], "import type { JWKSResponse } from '../models/JWKSResponse';")
+	assert.NotContains(t, files["adapters/authentication.ts"], "EmailVerificationStatus")
+	assert.Contains(t, files["adapters/authentication.ts"], "export function createAuthenticationAdapter(client: FetchClient): AuthenticationAdapter {")
+	assert.Contains(t, files["helpers.ts"], "export type ApiErrorResponse<S extends number, E>")
+
+	index := files["index.ts"]
+	assert.Contains(t, index, "import { createSsoAdapter } from './adapters/sso';")
+	assert.Contains(t, index, "export * from './models/UserIdentity';")
+	assert.Contains(t, index, "export type { ApiErrorResponse } from './helpers';")
+	assert.Contains(t, index, "export function createAdapter(client: FetchClient): AuthenticationAdapter & EmailVerificationAdapter & SsoAdapter {")
+	assert.Contains(t, index, "    ...createEmailVerificationAdapter(client),\n")
+}
+
+func TestShouldNestAdaptersGivenGroupByTagWhenGeneratingFilesThenKeyIndexAdapterByTag(t *testing.T) {
+	files := generateFilesFromFixture(t, "openapi-security.yaml", generator.Options{GroupByTag: true, Instance: "./client"})
+
+	adapter := files["adapters/default.ts"]
+	assert.Contains(t, adapter, "import type { FetchClient, FetchResponse } from '../client';")
+	assert.Contains(t, adapter, "import type { AdapterConfig } from '../helpers';\nimport { authorize } from '../helpers';")
+	assert.Contains(t, files["helpers.ts"], "export async function authorize(")
+	assert.Contains(t, files["helpers.ts"], "\nfunction applyCredential(")
+
+	index := files["index.ts"]
+	assert.Contains(t, index, "import type { FetchClient } from './client';")
+	assert.Contains(t, index, "export { SecuritySchemes, SecurityRequirements } from './helpers';")
+	assert.NotContains(t, index, "authorize")
+	assert.Contains(t, index, "config: AdapterConfig = {}): {\n  default: DefaultAdapter;\n} {")
+	assert.Contains(t, index, "    default: createDefaultAdapter(client, config),\n")
+}
+
+func TestShouldReExportResponseTypeErrorGivenDecodedResponsesWhenGeneratingFilesThenExposeClassFromIndex(t *testing.T) {
+	files := generateFilesFromFixture(t, "openapi-response-decoding.yaml", generator.Options{})
+
+	assert.Contains(t, files["helpers.ts"], "export class ResponseTypeError extends Error {")
+	assert.Contains(t, files["adapters/default.ts"], "import { checkResponseType, parseXmlResponse, readDownload } from '../helpers';")
+	assert.Contains(t, files["index.ts"], "export { ResponseTypeError } from './helpers';")
+}
+
+func TestShouldRenameSchemaGivenGlobalNameWhenGeneratingFilesThenKeepGlobalUnshadowed(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(strings.Join([]string{
+		"paths:",
+		"  /events:",
+		"    get:",
+		"      operationId: streamEvents",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"          content:",
+		"            text/event-stream:",
+		"              itemSchema:",
+		"                $ref: '#/components/schemas/Headers'",
+		"components:",
+		"  schemas:",
+		"    Headers:",
+		"      type: object",
+		"      properties:",
+		"        next:",
+		"          $ref: '#/components/schemas/n'",
+		"    n:",
+		"      type: object",
+		"      properties:",
+		"        value:",
+		"          type: string",
+	}, "\n")))
+	require.NoError(t, err)
+
+	var warnings []string
+	generated, err := generator.GenerateFiles(api, generator.Options{Warn: func(message string) { warnings = append(warnings, message) }})
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range generated {
+		files[file.Path] = string(file.Content)
+	}
+
+	assert.Contains(t, warnings, `schema "Headers" is generated as Headers2 so it does not shadow the global Headers`)
+	assert.NotContains(t, files, "models/Headers.ts")
+	assert.Contains(t, files["models/Headers2.ts"], "import type { n } from './n';\n\n/** Headers2 schema */\nexport interface Headers2 {")
+	assert.Contains(t, files["adapters/default.ts"], "import type { Headers2 } from '../models/Headers2';")
+	assert.Contains(t, files["adapters/default.ts"], "AsyncIterable<Headers2>")
+	// Neither the global Headers nor the n in the /\r\n|\n|\r(?!$)/ regular expression is a model reference.
+	assert.NotContains(t, files["helpers.ts"], "models/")
+	assert.Contains(t, files["index.ts"], "export * from './models/Headers2';")
+}
+
+func TestShouldSuffixGroupKeyGivenTagsNormalizingToSameKeyWhenGeneratingFilesThenKeepAdaptersApart(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(strings.Join([]string{
+		"paths:",
+		"  /accounts:",
+		"    get:",
+		"      operationId: listAccounts",
+		"      tags: [Billing Accounts]",
+		"      responses:",
+		"        \"204\":",
+		"          description: ok",
+		"  /invoices:",
+		"    get:",
+		"      operationId: listInvoices",
+		"      tags: [billing-accounts]",
+		"      responses:",
+		"        \"204\":",
+		"          description: ok",
+		"  /reports:",
+		"    get:",
+		"      operationId: listReports",
+		"      tags: [billingaccounts]",
+		"      responses:",
+		"        \"204\":",
+		"          description: ok",
+	}, "\n")))
+	require.NoError(t, err)
+
+	var warnings []string
+	generated, err := generator.GenerateFiles(api, generator.Options{GroupByTag: true, Warn: func(message string) { warnings = append(warnings, message) }})
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range generated {
+		files[file.Path] = string(file.Content)
+	}
+
+	assert.Equal(t, []string{
+		`tag "billing-accounts" is grouped as billingAccounts2, since another group is already named billingAccounts`,
+		`tag "billingaccounts" is grouped as billingaccounts3, since another group is already named billingaccounts`,
+	}, warnings)
+	assert.Contains(t, files["adapters/billingAccounts.ts"], "export function createBillingAccountsAdapter(client: FetchClient): BillingAccountsAdapter {")
+	assert.Contains(t, files["adapters/billingAccounts2.ts"], "listInvoices")
+	assert.Contains(t, files["adapters/billingaccounts3.ts"], "listReports")
+	assert.NotContains(t, files["adapters/billingAccounts.ts"], "listInvoices")
+	assert.Contains(t, files["index.ts"], "  billingAccounts: BillingAccountsAdapter;\n  billingAccounts2: BillingAccounts2Adapter;\n  billingaccounts3: Billingaccounts3Adapter;\n")
+}
+
+func TestShouldSuffixModelFileGivenSchemasDifferingOnlyInCaseWhenGeneratingFilesThenAvoidCaseInsensitiveCollision(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(strings.Join([]string{
+		"paths:",
+		"  /foos:",
+		"    get:",
+		"      operationId: listFoos",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"          content:",
+		"            application/json:",
+		"              schema:",
+		"                $ref: '#/components/schemas/foo'",
+		"components:",
+		"  schemas:",
+		"    Foo:",
+		"      type: object",
+		"      properties:",
+		"        id:",
+		"          type: string",
+		"    foo:",
+		"      type: object",
+		"      properties:",
+		"        items:",
+		"          type: array",
+		"          items:",
+		"            $ref: '#/components/schemas/Foo'",
+	}, "\n")))
+	require.NoError(t, err)
+
+	var warnings []string
+	generated, err := generator.GenerateFiles(api, generator.Options{Warn: func(message string) { warnings = append(warnings, message) }})
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range generated {
+		files[file.Path] = string(file.Content)
+	}
+
+	assert.Equal(t, []string{`schema "foo" is written to models/foo2.ts, since another schema's file name differs from it only in case`}, warnings)
+	assert.Contains(t, files["models/Foo.ts"], "export interface Foo {")
+	assert.Contains(t, files["models/foo2.ts"], "import type { Foo } from './Foo';\n\n/** foo schema */\nexport interface foo {")
+	assert.Contains(t, files["adapters/default.ts"], "import type { foo } from '../models/foo2';")
+	assert.Contains(t, files["index.ts"], "export * from './models/Foo';\nexport * from './models/foo2';")
+}
+
+func TestShouldExportStandaloneFunctionsGivenOperationsWhenGeneratingThenDelegateAdapterMethods(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-security.yaml")
+
+	assert.Contains(t, code, "export function getHealth(client: FetchClient, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }): Promise<FetchResponse<{ status?: string }>> {\n  const finalOptions = { ...options, operationId: options?.operationId ?? \"getHealth\" };\n  return client.get(`/health`, undefined, finalOptions);\n}")
+	assert.Contains(t, code, "export function listAccounts(client: FetchClient, query?: { page?: number }, options?: { signal?: AbortSignal; timeout?: number; operationId?: string }, config: AdapterConfig = {}): Promise<FetchResponse<Array<Account>>> {")
+	assert.Contains(t, code, " * @param config - Adapter configuration, as given to createAdapter\n")
+	assert.Contains(t, code, "      return getHealth(client, options);\n")
+	assert.Contains(t, code, "      return listAccounts(client, query, options, config);\n")
+}
+
+func TestShouldRenameStandaloneFunctionsGivenUnusableOperationIdsWhenGeneratingThenAvoidCollisions(t *testing.T) {
+	stringSchema := &apitypes.Schema{Type: apitypes.SchemaType{Values: []string{"string"}}}
+	code := generateCodeFromAPI(t, &apitypes.OpenAPI{
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/users/{user}": {
+				"get": {
+					OperationID: "user",
+					Parameters:  []*apitypes.Parameter{{Name: "user", In: "path", Required: true, Schema: stringSchema}},
+					Responses:   map[string]*apitypes.Response{"200": {Description: "ok"}},
+				},
+				"delete": {
+					OperationID: "delete",
+					Parameters:  []*apitypes.Parameter{{Name: "user", In: "path", Required: true, Schema: stringSchema}},
+					Responses:   map[string]*apitypes.Response{"204": {Description: "No Content"}},
+				},
+			},
+			"/user-search": {
+				"get": {
+					OperationID: "search-users",
+					Responses:   map[string]*apitypes.Response{"200": {Description: "ok"}},
+				},
+			},
+		},
+	})
+
+	assert.Contains(t, code, "export function userOperation(client: FetchClient, user: string,")
+	assert.Contains(t, code, "      return userOperation(client, user, options);\n")
+	assert.Contains(t, code, "export function deleteOperation(client: FetchClient, user: string,")
+	assert.Contains(t, code, "export function searchUsers(client: FetchClient, options?:")
+	assert.Contains(t, code, `"search-users": (options?:`)
+}
+
+func TestShouldKeepTaggedOperationsGivenIncludeTagsWhenGeneratingThenPruneUnreachableSchemas(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "auth-api.yaml", generator.Options{IncludeTags: []string{"Authentication"}, ExcludePaths: []string{"/auth/login/*"}})
+
+	assert.Contains(t, code, "export function getJWKS(")
+	assert.Contains(t, code, "export function logout(")
+	assert.NotContains(t, code, "export function ssoLogin(")
+	assert.NotContains(t, code, "export function detectSSOProviders(")
+	assert.NotContains(t, code, "export function verifyEmail(")
+	assert.Contains(t, code, "export interface JWKSResponse {")
+	assert.Contains(t, code, "export interface JWKResponse {")
+	assert.NotContains(t, code, "EmailVerificationStatus")
+}
+
+func TestShouldSelectOperationsGivenOperationIdsAndPathGlobsWhenGeneratingThenWarnAboutUnknownIds(t *testing.T) {
+	fixturePath, err := filepath.Abs(filepath.Join("..", "..", "tests", "fixtures", "auth-api.yaml"))
+	require.NoError(t, err)
+	content, err := os.ReadFile(fixturePath)
+	require.NoError(t, err)
+	api, err := parser.ParseDocument(fixturePath, content)
+	require.NoError(t, err)
+
+	warnings := []string{}
+	output, err := generator.Generate(api, generator.Options{
+		IncludeOperations: []string{"verifyEmail", "resendVerification", "getCurrentUser", "missingOperation"},
+		IncludePaths:      []string{"/auth/verify/**"},
+		Warn: func(message string) {
+			warnings = append(warnings, message)
+		},
+	})
+	require.NoError(t, err)
+	code := string(output)
+
+	assert.Contains(t, code, "export function verifyEmail(")
+	assert.Contains(t, code, "export function resendVerification(")
+	assert.NotContains(t, code, "export function getCurrentUser(")
+	assert.Equal(t, []string{`no operation has operationId "missingOperation"`}, warnings)
+	assert.Len(t, api.Paths, 9)
+}
+
+func TestShouldReturnErrorGivenMalformedPathGlobWhenGeneratingThenFail(t *testing.T) {
+	_, err := generator.Generate(&apitypes.OpenAPI{}, generator.Options{ExcludePaths: []string{"/users/[id"}})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `invalid path pattern "/users/[id"`)
+}
+
+func TestShouldPruneUnreferencedSchemasGivenPruneSchemasWhenGeneratingThenKeepThemByDefault(t *testing.T) {
+	objectType := apitypes.SchemaType{Values: []string{"object"}}
+	api := &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"User": {
+					Type:       objectType,
+					Properties: map[string]*apitypes.Schema{"address": {Ref: "#/components/schemas/Address"}},
+				},
+				"Address": {Type: objectType, Properties: map[string]*apitypes.Schema{}},
+				"Orphan":  {Type: objectType, Properties: map[string]*apitypes.Schema{}},
+			},
+		},
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/users": {
+				"get": {
+					OperationID: "listUsers",
+					Responses: map[string]*apitypes.Response{
+						"200": {Content: map[string]apitypes.MediaType{"application/json": {Schema: &apitypes.Schema{
+							Type:  apitypes.SchemaType{Values: []string{"array"}},
+							Items: &apitypes.BooleanSchema{Schema: &apitypes.Schema{Ref: "#/components/schemas/User"}},
+						}}}},
+					},
+				},
+			},
+		},
+	}
+
+	code := generateCodeFromAPI(t, api)
+	assert.Contains(t, code, "export type Orphan = ")
+
+	code = generateCodeFromAPIWithOptions(t, api, generator.Options{PruneSchemas: true})
+	assert.Contains(t, code, "export interface User {")
+	assert.Contains(t, code, "export type Address = ")
+	assert.NotContains(t, code, "Orphan")
+	assert.Len(t, api.Components.Schemas, 3)
+}
+
+func TestShouldMarkDeprecatedMembersGivenDeprecatedFlagsWhenGeneratingThenEmitDeprecatedTags(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-deprecated.yaml")
+
+	assert.Contains(t, code, "/**\n * GET /users/${encodeURIComponent(String(id))}\n *\n * @deprecated\n * @param client")
+	assert.Contains(t, code, "  /**\n   * GET /users/${encodeURIComponent(String(id))}\n   *\n   * @deprecated\n")
+	assert.Contains(t, code, "@param id - User identifier (deprecated)")
+	assert.Contains(t, code, "query?: { page?: number; /** @deprecated */ sort?: string }")
+	assert.Contains(t, code, "/** @deprecated Use id instead */\n\tlogin?: string;")
+	assert.Contains(t, code, "profile?: { /** @deprecated */ nickname?: string };")
+	assert.Contains(t, code, "/** @deprecated LegacyUser schema */\nexport interface LegacyUser {")
+}
+
+func TestShouldOmitDeprecatedOperationsGivenExcludeDeprecatedWhenGeneratingThenKeepRequiredMembers(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "openapi-deprecated.yaml", generator.Options{ExcludeDeprecated: true})
+
+	assert.Contains(t, code, "export function listUsers(client: FetchClient, query?: { page?: number }, ")
+	assert.NotContains(t, code, "getUserLegacy")
+	assert.NotContains(t, code, "LegacyUser")
+	assert.NotContains(t, code, "login")
+	assert.Contains(t, code, "profile?: Record<string, any>;")
+	assert.Contains(t, code, "/** @deprecated */\n\ttenant: string;")
+}
+
+func TestShouldHideInternalMembersGivenExtensionsWhenGeneratingThenSkipOperationsSchemasAndProperties(t *testing.T) {
+	code := generateCodeFromFixture(t, "openapi-internal.yaml")
+
+	assert.Contains(t, code, "export function getUser(")
+	assert.NotContains(t, code, "purgeUsers")
+	assert.NotContains(t, code, "debugDump")
+	assert.Contains(t, code, "export interface User {\n\tid: string;\n}")
+	assert.NotContains(t, code, "AuditTrail")
+	assert.NotContains(t, code, "PurgeReport")
+	assert.NotContains(t, code, "Feature")
+}
+
+func TestShouldIncludeInternalMembersGivenIncludeInternalWhenGeneratingThenStillSkipIgnoredOnes(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "openapi-internal.yaml", generator.Options{IncludeInternal: true})
+
+	assert.Contains(t, code, "export function purgeUsers(")
+	assert.Contains(t, code, "riskScore?: number;")
+	assert.Contains(t, code, "export interface PurgeReport {")
+	assert.Contains(t, code, "export interface Feature {")
+	assert.NotContains(t, code, "debugDump")
+	assert.NotContains(t, code, "audit?:")
+	assert.NotContains(t, code, "AuditTrail")
+}
+
+func TestShouldWarnGivenReferencedInternalSchemaWhenGeneratingThenKeepIt(t *testing.T) {
+	objectType := apitypes.SchemaType{Values: []string{"object"}}
+	api := &apitypes.OpenAPI{
+		Components: apitypes.Components{
+			Schemas: map[string]*apitypes.Schema{
+				"Secret": {Type: objectType, Properties: map[string]*apitypes.Schema{}, Extensions: apitypes.Extensions{"x-internal": true}},
+			},
+		},
+		Paths: map[string]map[string]*apitypes.Operation{
+			"/secret": {
+				"get": {
+					OperationID: "getSecret",
+					Responses: map[string]*apitypes.Response{
+						"200": {Content: map[string]apitypes.MediaType{"application/json": {Schema: &apitypes.Schema{Ref: "#/components/schemas/Secret"}}}},
+					},
+				},
+			},
+		},
+	}
+
+	warnings := []string{}
+	output, err := generator.Generate(api, generator.Options{Warn: func(message string) {
+		warnings = append(warnings, message)
+	}})
+	require.NoError(t, err)
+
+	assert.Contains(t, string(output), "export type Secret = ")
+	assert.Equal(t, []string{`schema "Secret" is hidden by an extension but a generated operation references it`}, warnings)
+}
+
+func TestShouldOverrideTypesGivenFormatTableAndTsTypeExtensionsWhenGeneratingThenImportThem(t *testing.T) {
+	code := generateCodeFromFixtureWithOptions(t, "openapi-type-overrides.yaml", generator.Options{FormatTypes: map[string]generator.TypeOverride{
+		"date-time": {Type: "IsoDateString", Module: "./branded"},
+		"int64":     {Type: "bigint"},
+		"uuid":      {Type: "Uuid", Module: "./ids"},
+	}})
+
+	assert.Contains(t, code, "// Auto-generated by fetch-gen\n"+
+		"import type { FetchClient, FetchResponse } from '@fgrzl/fetch';\n"+
+		"import { buildQueryParams } from '@fgrzl/fetch';\n"+
+		"import type { IsoDateString } from './branded';\n"+
+		"import type { Money } from './money';\n\n")
+	assert.NotContains(t, code, "Uuid")
+	assert.Contains(t, code, "export function getInvoice(client: FetchClient, id: bigint, ")
+	assert.Contains(t, code, "\tid: bigint;")
+	assert.Contains(t, code, "\tissuedAt: IsoDateString;")
+	assert.Contains(t, code, "\tpaidAt?: IsoDateString | null;")
+	assert.Contains(t, code, "\ttotal: Money;")
+	assert.Contains(t, code, "export type Currency = 'USD' | 'EUR';")
+}
+
+func TestShouldImportOverrideTypesGivenOutputFilesWhenGeneratingFilesThenResolveFromSubdirectories(t *testing.T) {
+	files := generateFilesFromFixture(t, "openapi-type-overrides.yaml", generator.Options{FormatTypes: map[string]generator.TypeOverride{
+		"date-time": {Type: "IsoDateString", Module: "./branded"},
+	}})
+
+	assert.Contains(t, files["models/Invoice.ts"], "import type { IsoDateString } from '../branded';\nimport type { Money } from '../money';\nimport type { Currency } from './Currency';\n")
+	assert.NotContains(t, files["models/Currency.ts"], "import")
+	assert.NotContains(t, files["index.ts"], "branded")
+}
@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	apitypes "github.com/fgrzl/fetch-gen/internal/types"
+)
+
+// DefaultMediaTypes is the media type preference used when Options.MediaTypes is empty: plain JSON,
+// then any structured +json type (application/problem+json, application/vnd.api+json), then anything.
+var DefaultMediaTypes = []string{"application/json", "*/*+json", "*/*"}
+
+// mediaTypePreference orders the media types a request or response body declares, most preferred
+// first. Entries are exact media types or patterns such as "text/*", "application/*+json" and "*/*".
+type mediaTypePreference []string
+
+func newMediaTypePreference(patterns []string) (mediaTypePreference, error) {
+	if len(patterns) == 0 {
+		return mediaTypePreference(DefaultMediaTypes), nil
+	}
+	pref := make(mediaTypePreference, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = normalizeMediaType(pattern)
+		mediaType, subtype, ok := strings.Cut(pattern, "/")
+		if !ok || mediaType == "" || subtype == "" || (mediaType == "*" && subtype != "*" && !strings.HasPrefix(subtype, "*+")) {
+			return nil, fmt.Errorf("invalid media type preference %q", pattern)
+		}
+		pref = append(pref, pattern)
+	}
+	return pref, nil
+}
+
+// pick returns the most preferred of keys, breaking ties alphabetically. Keys no pattern matches are
+// never picked, so a preference list without "*/*" can exclude media types entirely.
+func (p mediaTypePreference) pick(keys []string) (string, bool) {
+	for _, pattern := range p {
+		for _, key := range keys {
+			if mediaTypeMatches(pattern, key) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requestMediaType returns the preferred media type of a request body that declares a schema.
+func (p mediaTypePreference) requestMediaType(content map[string]*apitypes.MediaType) (string, bool) {
+	keys := []string{}
+	for _, key := range apitypes.SortedKeys(content) {
+		if media := content[key]; media != nil && media.Schema != nil {
+			keys = append(keys, key)
+		}
+	}
+	return p.pick(keys)
+}
+
+func (p mediaTypePreference) requestSchema(content map[string]*apitypes.MediaType) *apitypes.Schema {
+	if key, ok := p.requestMediaType(content); ok {
+		return content[key].Schema
+	}
+	return nil
+}
+
+// responseMediaTypes returns the media types of a response that declare a schema, most preferred first.
+func (p mediaTypePreference) responseMediaTypes(content map[string]apitypes.MediaType) []string {
+	remaining := []string{}
+	for _, key := range apitypes.SortedKeys(content) {
+		if content[key].Schema != nil || content[key].StreamItemSchema() != nil {
+			remaining = append(remaining, key)
+		}
+	}
+	ordered := []string{}
+	for len(remaining) > 0 {
+		key, ok := p.pick(remaining)
+		if !ok {
+			break
+		}
+		ordered = append(ordered, key)
+		for i, candidate := range remaining {
+			if candidate == key {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+func (p mediaTypePreference) responseMediaType(content map[string]apitypes.MediaType) (string, bool) {
+	if keys := p.responseMediaTypes(content); len(keys) > 0 {
+		return keys[0], true
+	}
+	return "", false
+}
+
+func (p mediaTypePreference) responseSchema(content map[string]apitypes.MediaType) *apitypes.Schema {
+	if key, ok := p.responseMediaType(content); ok {
+		return content[key].Schema
+	}
+	return nil
+}
+
+// mediaTypeMatches reports whether mediaType satisfies pattern. Parameters such as charset are ignored
+// and a "*+suffix" subtype matches structured syntax suffixes, so "*/*+json" matches "application/problem+json".
+func mediaTypeMatches(pattern, mediaType string) bool {
+	patternType, patternSubtype, _ := strings.Cut(normalizeMediaType(pattern), "/")
+	actualType, actualSubtype, ok := strings.Cut(normalizeMediaType(mediaType), "/")
+	if !ok {
+		return false
+	}
+	if patternType != "*" && patternType != actualType {
+		return false
+	}
+	switch {
+	case patternSubtype == "*":
+		return true
+	case strings.HasPrefix(patternSubtype, "*+"):
+		return strings.HasSuffix(actualSubtype, patternSubtype[1:])
+	default:
+		return patternSubtype == actualSubtype
+	}
+}
+
+// streamMediaTypes are sequential media types whose bodies are read incrementally, one item at a time.
+var streamMediaTypes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+	"application/ndjson",
+	"application/jsonl",
+	"application/x-jsonlines",
+}
+
+// streamFormat returns "sse" for Server-Sent Events, "ndjson" for newline-delimited JSON, or "" when
+// mediaType is not streamed.
+func streamFormat(mediaType string) string {
+	switch {
+	case mediaTypeMatches("text/event-stream", mediaType):
+		return "sse"
+	case slices.ContainsFunc(streamMediaTypes, func(pattern string) bool { return mediaTypeMatches(pattern, mediaType) }):
+		return "ndjson"
+	}
+	return ""
+}
+
+// arrayBufferMediaTypes are binary encodings callers decode in memory rather than save as files.
+var arrayBufferMediaTypes = []string{
+	"application/cbor",
+	"application/msgpack",
+	"application/protobuf",
+	"application/vnd.google.protobuf",
+	"application/vnd.msgpack",
+	"application/x-msgpack",
+	"application/x-protobuf",
+}
+
+// responseDecoding returns the responseType the client should read a body of mediaType with:
+// "text", "blob" or "arrayBuffer", or "" for the client's default JSON decoding.
+func responseDecoding(mediaType string, schema *apitypes.Schema) string {
+	switch {
+	case isJSONMediaType(mediaType):
+		return ""
+	case slices.ContainsFunc(arrayBufferMediaTypes, func(pattern string) bool { return mediaTypeMatches(pattern, mediaType) }):
+		return "arrayBuffer"
+	case isBinarySchema(schema):
+		return "blob"
+	case isXMLMediaType(mediaType), mediaTypeMatches("text/*", mediaType):
+		return "text"
+	case schema != nil && schema.Type.Has("string"):
+		return "text"
+	}
+	return ""
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaTypeMatches("application/json", mediaType) || mediaTypeMatches("*/*+json", mediaType)
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaTypeMatches("application/xml", mediaType) || mediaTypeMatches("text/xml", mediaType) || mediaTypeMatches("*/*+xml", mediaType)
+}
+
+// isXMLDocument reports whether an XML body describes structured content, which the adapter parses
+// into a Document; XML declared as a plain string stays a string.
+func isXMLDocument(mediaType string, schema *apitypes.Schema) bool {
+	return isXMLMediaType(mediaType) && schema != nil && !schema.Type.Has("string")
+}
+
+func normalizeMediaType(mediaType string) string {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
@@ -0,0 +1,240 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	apitypes "github.com/fgrzl/fetch-gen/internal/types"
+)
+
+// typeResolver maps schemas to TypeScript types. Component schemas and inline schemas that close a
+// cycle without a $ref are named up front, so resolution always terminates and recursive shapes are
+// emitted as named types instead of being expanded forever.
+type typeResolver struct {
+	names map[*apitypes.Schema]string
+	taken map[string]struct{}
+	// schemaTypes maps the component schemas renamed to keep clear of reservedTypeNames to their new names.
+	schemaTypes map[string]string
+	helpers     map[string]string
+	hoisted     []templateSchema
+	media       mediaTypePreference
+	// includeInternal keeps properties marked x-internal; excludeDeprecated drops optional deprecated
+	// properties.
+	includeInternal   bool
+	excludeDeprecated bool
+	// formats maps schema formats to the types they generate; imports maps the names of the override
+	// types emitted so far to the modules they are imported from.
+	formats map[string]TypeOverride
+	imports map[string]string
+}
+
+func newTypeResolver(api *apitypes.OpenAPI, media mediaTypePreference, opts Options) *typeResolver {
+	r := &typeResolver{
+		media:             media,
+		includeInternal:   opts.IncludeInternal,
+		excludeDeprecated: opts.ExcludeDeprecated,
+		formats:           opts.FormatTypes,
+		imports:           map[string]string{},
+		names:             map[*apitypes.Schema]string{},
+		taken:             map[string]struct{}{},
+		schemaTypes:       map[string]string{},
+		helpers:           map[string]string{},
+	}
+
+	for _, name := range reservedTypeNames {
+		r.taken[name] = struct{}{}
+	}
+	schemaNames := apitypes.SortedKeys(api.Components.Schemas)
+	for _, name := range schemaNames {
+		r.taken[name] = struct{}{}
+	}
+	for _, name := range schemaNames {
+		if slices.Contains(reservedTypeNames, name) {
+			r.schemaTypes[name] = r.reserveName(name)
+			if opts.Warn != nil {
+				opts.Warn(fmt.Sprintf("schema %q is generated as %s so it does not shadow the global %s", name, r.schemaTypes[name], name))
+			}
+		}
+		if schema := api.Components.Schemas[name]; schema != nil {
+			if _, named := r.names[schema]; !named {
+				r.names[schema] = r.schemaTypeName(name)
+			}
+		}
+	}
+
+	visited := map[*apitypes.Schema]struct{}{}
+	for _, name := range schemaNames {
+		r.hoistCycles(api.Components.Schemas[name], r.schemaTypeName(name), visited, map[*apitypes.Schema]struct{}{})
+	}
+
+	hoistRoles := map[apitypes.SchemaRole]string{
+		apitypes.ParameterSchema:      "Param",
+		apitypes.RequestBodySchema:    "Request",
+		apitypes.ResponseHeaderSchema: "Header",
+		apitypes.ResponseSchema:       "Response",
+		apitypes.ResponseItemSchema:   "Item",
+	}
+	for op := range api.Operations() {
+		owner := apitypes.PascalCase(op.Operation.OperationID)
+		for schema := range op.Operation.Schemas() {
+			r.hoistCycles(schema.Schema, owner+hoistRoles[schema.Role], visited, map[*apitypes.Schema]struct{}{})
+		}
+	}
+
+	sort.Slice(r.hoisted, func(i, j int) bool { return r.hoisted[i].Name < r.hoisted[j].Name })
+	return r
+}
+
+// hoistCycles walks s depth-first and names every schema reached again while it is still on the
+// stack; naming each back-edge target is enough to break every cycle.
+func (r *typeResolver) hoistCycles(s *apitypes.Schema, owner string, visited, stack map[*apitypes.Schema]struct{}) {
+	if s == nil {
+		return
+	}
+	if _, onStack := stack[s]; onStack {
+		if _, named := r.names[s]; !named {
+			r.hoist(s, owner)
+		}
+		return
+	}
+	if _, ok := visited[s]; ok {
+		return
+	}
+	visited[s] = struct{}{}
+	stack[s] = struct{}{}
+	for _, sub := range s.Subschemas() {
+		r.hoistCycles(sub, owner, visited, stack)
+	}
+	delete(stack, s)
+}
+
+func (r *typeResolver) hoist(s *apitypes.Schema, owner string) {
+	name := r.reserveName(owner + "Node")
+	r.names[s] = name
+	r.hoisted = append(r.hoisted, templateSchema{Name: name, Schema: s, PropKeys: r.propertyKeys(s)})
+}
+
+// reservedTypeNames are the global and fetch client names the generated code uses as types or values. A
+// schema of the same name would shadow them, so it is generated under another name, and the
+// multi-file output never imports a model where the code means the global.
+var reservedTypeNames = []string{
+	"AbortSignal", "Array", "ArrayBuffer", "AsyncGenerator", "AsyncIterable", "Blob", "BlobPart", "BodyInit",
+	"DOMParser", "Document", "Error", "Exclude", "File", "FormData", "Headers", "HeadersInit", "JSON", "Number",
+	"Object", "Omit", "Partial", "Promise", "RangeError", "ReadableStream", "Record", "RequestInit", "String",
+	"TextDecoderStream", "URLSearchParams", "Uint8Array",
+	"FetchClient", "FetchResponse",
+}
+
+// schemaTypeName is the emitted name of the component schema name.
+func (r *typeResolver) schemaTypeName(name string) string {
+	if renamed, ok := r.schemaTypes[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// reserveName returns base, or base with a numeric suffix when a schema or helper already uses it.
+func (r *typeResolver) reserveName(base string) string {
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := r.taken[name]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	r.taken[name] = struct{}{}
+	return name
+}
+
+// helperTypeName returns the emitted name of a generated helper type such as ApiErrorResponse,
+// reserving it on first use so it never shadows a component schema.
+func (r *typeResolver) helperTypeName(base string) string {
+	if name, ok := r.helpers[base]; ok {
+		return name
+	}
+	name := r.reserveName(base)
+	r.helpers[base] = name
+	return name
+}
+
+// tsTypeExtension returns the type the x-ts-type extension of s sets, with the module x-ts-import names.
+func tsTypeExtension(s *apitypes.Schema) (TypeOverride, bool) {
+	if s == nil {
+		return TypeOverride{}, false
+	}
+	name, _ := s.Extensions[tsTypeExtensionName].(string)
+	if strings.TrimSpace(name) == "" {
+		return TypeOverride{}, false
+	}
+	module, _ := s.Extensions[tsImportExtensionName].(string)
+	return TypeOverride{Type: strings.TrimSpace(name), Module: strings.TrimSpace(module)}, true
+}
+
+// useOverride returns the type of override, recording the import of its leading identifier when it
+// comes from a module.
+func (r *typeResolver) useOverride(override TypeOverride) string {
+	if override.Module != "" {
+		end := strings.IndexFunc(override.Type, func(c rune) bool {
+			return c != '_' && c != '$' && !unicode.IsLetter(c) && !unicode.IsDigit(c)
+		})
+		if end < 0 {
+			end = len(override.Type)
+		}
+		if name := override.Type[:end]; isTSIdentifier(name) {
+			r.imports[name] = override.Module
+		}
+	}
+	return override.Type
+}
+
+// typeImports groups the imports of the override types emitted so far by module.
+func (r *typeResolver) typeImports() []typeImport {
+	byModule := map[string][]string{}
+	for name, module := range r.imports {
+		byModule[module] = append(byModule[module], name)
+	}
+	imports := make([]typeImport, 0, len(byModule))
+	for _, module := range apitypes.SortedKeys(byModule) {
+		names := byModule[module]
+		sort.Strings(names)
+		imports = append(imports, typeImport{Module: module, Names: names})
+	}
+	return imports
+}
+
+func (r *typeResolver) resolveType(s *apitypes.Schema) string {
+	if s != nil {
+		if name, ok := r.names[s]; ok {
+			return name
+		}
+	}
+	return r.expandType(s)
+}
+
+// resolveDefinition expands a named schema itself; nested references to it still resolve to its name.
+func (r *typeResolver) resolveDefinition(s *apitypes.Schema) string {
+	return r.expandType(s)
+}
+
+// propertyKeys returns the sorted names of the properties of s that are not hidden by an extension, or
+// left out as optional and deprecated.
+func (r *typeResolver) propertyKeys(s *apitypes.Schema) []string {
+	keys := []string{}
+	if s == nil {
+		return keys
+	}
+	for _, name := range apitypes.SortedKeys(s.Properties) {
+		prop := s.Properties[name]
+		if prop != nil && hidden(prop.Extensions, r.includeInternal) {
+			continue
+		}
+		if prop != nil && prop.Deprecated && r.excludeDeprecated && !containsString(s.Required, name) {
+			continue
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
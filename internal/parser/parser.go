@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	apitypes "github.com/fgrzl/fetch-gen/internal/types"
@@ -38,6 +39,10 @@ func ParseDocument(inputPath string, data []byte) (*apitypes.OpenAPI, error) {
 		return nil, fmt.Errorf("unsupported file type (must be .yaml or .json)")
 	}
 
+	if err := resolveSchemaRefs(&api); err != nil {
+		return nil, err
+	}
+
 	if err := validateOpenAPI(&api); err != nil {
 		return nil, err
 	}
@@ -65,6 +70,25 @@ func validateOpenAPI(api *apitypes.OpenAPI) error {
 		}
 	}
 
+	for name, header := range api.Components.Headers {
+		headerPath := fmt.Sprintf("components.headers[%q]", name)
+		if err := validateHeader(api, headerPath, header, componentNames); err != nil {
+			return err
+		}
+	}
+
+	for name, scheme := range api.Components.SecuritySchemes {
+		if err := validateSecurityScheme(fmt.Sprintf("components.securitySchemes[%q]", name), scheme); err != nil {
+			return err
+		}
+	}
+	if err := validateSecurityRequirements(api, "security", api.Security); err != nil {
+		return err
+	}
+	if err := validateServers("servers", api.Servers); err != nil {
+		return err
+	}
+
 	seenOperationIDs := map[string]string{}
 	for path, methods := range api.Paths {
 		pathParams, err := extractPathTemplateParams(path)
@@ -72,6 +96,10 @@ func validateOpenAPI(api *apitypes.OpenAPI) error {
 			return validationError{Path: fmt.Sprintf("paths[%q]", path), Message: err.Error()}
 		}
 
+		if err := validateServers(fmt.Sprintf("paths[%q].servers", path), api.PathServers[path]); err != nil {
+			return err
+		}
+
 		pathParamSet := map[string]struct{}{}
 		for _, name := range pathParams {
 			pathParamSet[name] = struct{}{}
@@ -89,6 +117,14 @@ func validateOpenAPI(api *apitypes.OpenAPI) error {
 				return validationError{Path: opPath + ".operationId", Message: fmt.Sprintf("duplicate operationId already used at %s", previousPath)}
 			}
 			seenOperationIDs[op.OperationID] = opPath
+			if op.Security != nil {
+				if err := validateSecurityRequirements(api, opPath+".security", *op.Security); err != nil {
+					return err
+				}
+			}
+			if err := validateServers(opPath+".servers", op.Servers); err != nil {
+				return err
+			}
 
 			seenPathParams := map[string]struct{}{}
 			for i, param := range op.Parameters {
@@ -132,10 +168,13 @@ func validateOpenAPI(api *apitypes.OpenAPI) error {
 					if err := validateSchema(contentPath+".schema", media.Schema, componentNames, map[*apitypes.Schema]struct{}{}); err != nil {
 						return err
 					}
+					if err := validateEncoding(contentPath+".encoding", media.Encoding); err != nil {
+						return err
+					}
 				}
 			}
 
-			if err := validateResponses(opPath, op.Responses, componentNames); err != nil {
+			if err := validateResponses(api, opPath, op.Responses, componentNames); err != nil {
 				return err
 			}
 		}
@@ -192,7 +231,108 @@ func resolveParameter(api *apitypes.OpenAPI, param *apitypes.Parameter, seen map
 	return resolveParameter(api, component, seen)
 }
 
-func validateResponses(opPath string, responses map[string]*apitypes.Response, componentNames map[string]struct{}) error {
+func validateServers(path string, servers []apitypes.Server) error {
+	for i, server := range servers {
+		serverPath := fmt.Sprintf("%s[%d]", path, i)
+		if strings.TrimSpace(server.URL) == "" {
+			return validationError{Path: serverPath + ".url", Message: "missing server url"}
+		}
+		names, err := extractPathTemplateParams(server.URL)
+		if err != nil {
+			return validationError{Path: serverPath + ".url", Message: err.Error()}
+		}
+		for _, name := range names {
+			if _, ok := server.Variables[name]; !ok {
+				return validationError{Path: serverPath + ".url", Message: fmt.Sprintf("undeclared server variable %q", name)}
+			}
+		}
+		for name, variable := range server.Variables {
+			variablePath := fmt.Sprintf("%s.variables[%q]", serverPath, name)
+			if variable == nil {
+				return validationError{Path: variablePath, Message: "server variable is null"}
+			}
+			if len(variable.Enum) > 0 && !slices.Contains(variable.Enum, variable.Default) {
+				return validationError{Path: variablePath + ".default", Message: fmt.Sprintf("default %q is not one of the enum values", variable.Default)}
+			}
+		}
+	}
+	return nil
+}
+
+func validateSecurityScheme(path string, scheme *apitypes.SecurityScheme) error {
+	if scheme == nil {
+		return validationError{Path: path, Message: "security scheme is null"}
+	}
+	switch scheme.Type {
+	case "apiKey":
+		if strings.TrimSpace(scheme.Name) == "" {
+			return validationError{Path: path + ".name", Message: "missing apiKey name"}
+		}
+		switch scheme.In {
+		case "header", "query", "cookie":
+		default:
+			return validationError{Path: path + ".in", Message: fmt.Sprintf("unsupported apiKey location %q", scheme.In)}
+		}
+	case "http":
+		if strings.TrimSpace(scheme.Scheme) == "" {
+			return validationError{Path: path + ".scheme", Message: "missing http scheme"}
+		}
+	case "oauth2", "openIdConnect", "mutualTLS":
+	default:
+		return validationError{Path: path + ".type", Message: fmt.Sprintf("unsupported security scheme type %q", scheme.Type)}
+	}
+	return nil
+}
+
+func validateSecurityRequirements(api *apitypes.OpenAPI, path string, requirements []apitypes.SecurityRequirement) error {
+	for i, requirement := range requirements {
+		for name := range requirement {
+			if _, ok := api.Components.SecuritySchemes[name]; !ok {
+				return validationError{Path: fmt.Sprintf("%s[%d][%q]", path, i, name), Message: fmt.Sprintf("unknown security scheme %q", name)}
+			}
+		}
+	}
+	return nil
+}
+
+func validateHeader(api *apitypes.OpenAPI, path string, header *apitypes.Header, componentNames map[string]struct{}) error {
+	resolved, err := resolveHeader(api, header, map[string]struct{}{})
+	if err != nil {
+		return validationError{Path: path, Message: err.Error()}
+	}
+	if resolved.Schema == nil {
+		return validationError{Path: path + ".schema", Message: "missing schema"}
+	}
+	return validateSchema(path+".schema", resolved.Schema, componentNames, map[*apitypes.Schema]struct{}{})
+}
+
+func resolveHeader(api *apitypes.OpenAPI, header *apitypes.Header, seen map[string]struct{}) (*apitypes.Header, error) {
+	if header == nil {
+		return nil, fmt.Errorf("header is null")
+	}
+	if header.Ref == "" {
+		return header, nil
+	}
+	const prefix = "#/components/headers/"
+	if !strings.HasPrefix(header.Ref, prefix) {
+		return nil, fmt.Errorf("unsupported header ref %q", header.Ref)
+	}
+	name := strings.TrimPrefix(header.Ref, prefix)
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("unsupported header ref %q", header.Ref)
+	}
+	if _, ok := seen[name]; ok {
+		return nil, fmt.Errorf("cyclic header ref %q", header.Ref)
+	}
+	component, ok := api.Components.Headers[name]
+	if !ok {
+		return nil, fmt.Errorf("unresolved header ref %q", header.Ref)
+	}
+	seen[name] = struct{}{}
+	return resolveHeader(api, component, seen)
+}
+
+func validateResponses(api *apitypes.OpenAPI, opPath string, responses map[string]*apitypes.Response, componentNames map[string]struct{}) error {
 	if len(responses) == 0 {
 		return validationError{Path: opPath + ".responses", Message: "missing responses"}
 	}
@@ -202,16 +342,33 @@ func validateResponses(opPath string, responses map[string]*apitypes.Response, c
 		if resp == nil {
 			return validationError{Path: responsePath, Message: "response is null"}
 		}
+		for name, header := range resp.Headers {
+			if err := validateHeader(api, fmt.Sprintf("%s.headers[%q]", responsePath, name), header, componentNames); err != nil {
+				return err
+			}
+		}
 		if len(resp.Content) == 0 {
 			continue
 		}
 		for contentType, media := range resp.Content {
 			contentPath := fmt.Sprintf("%s.content[%q]", responsePath, contentType)
-			if media.Schema == nil {
+			if media.Schema == nil && media.StreamItemSchema() == nil {
 				return validationError{Path: contentPath + ".schema", Message: "missing schema"}
 			}
-			if err := validateSchema(contentPath+".schema", media.Schema, componentNames, map[*apitypes.Schema]struct{}{}); err != nil {
-				return err
+			if media.Schema != nil {
+				if err := validateSchema(contentPath+".schema", media.Schema, componentNames, map[*apitypes.Schema]struct{}{}); err != nil {
+					return err
+				}
+			}
+			if media.ItemSchema != nil {
+				if err := validateSchema(contentPath+".itemSchema", media.ItemSchema, componentNames, map[*apitypes.Schema]struct{}{}); err != nil {
+					return err
+				}
+			}
+			if media.StreamItem != nil {
+				if err := validateSchema(contentPath+`["x-stream-item"]`, media.StreamItem, componentNames, map[*apitypes.Schema]struct{}{}); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -219,6 +376,20 @@ func validateResponses(opPath string, responses map[string]*apitypes.Response, c
 	return nil
 }
 
+func validateEncoding(path string, encoding map[string]*apitypes.Encoding) error {
+	for name, enc := range encoding {
+		if enc == nil {
+			continue
+		}
+		switch enc.Style {
+		case "", "form", "spaceDelimited", "pipeDelimited", "deepObject":
+		default:
+			return validationError{Path: fmt.Sprintf("%s[%q].style", path, name), Message: fmt.Sprintf("unsupported encoding style %q", enc.Style)}
+		}
+	}
+	return nil
+}
+
 func validateSchema(path string, s *apitypes.Schema, componentNames map[string]struct{}, seen map[*apitypes.Schema]struct{}) error {
 	if s == nil {
 		return validationError{Path: path, Message: "missing schema"}
@@ -267,12 +438,37 @@ func validateSchema(path string, s *apitypes.Schema, componentNames map[string]s
 		}
 	}
 
-	if s.Items != nil {
-		if err := validateSchema(path+".items", s.Items, componentNames, seen); err != nil {
+	if s.Items != nil && s.Items.Schema != nil {
+		if err := validateSchema(path+".items", s.Items.Schema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	for i, subSchema := range s.PrefixItems {
+		if subSchema == nil {
+			return validationError{Path: fmt.Sprintf("%s.prefixItems[%d]", path, i), Message: "schema is null"}
+		}
+		if err := validateSchema(fmt.Sprintf("%s.prefixItems[%d]", path, i), subSchema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	if s.Contains != nil {
+		if err := validateSchema(path+".contains", s.Contains, componentNames, seen); err != nil {
 			return err
 		}
 	}
 
+	if s.MinItems != nil && *s.MinItems < 0 {
+		return validationError{Path: path + ".minItems", Message: "minItems must not be negative"}
+	}
+	if s.MaxItems != nil && *s.MaxItems < 0 {
+		return validationError{Path: path + ".maxItems", Message: "maxItems must not be negative"}
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MinItems > *s.MaxItems {
+		return validationError{Path: path + ".minItems", Message: "minItems must not exceed maxItems"}
+	}
+
 	for i, subSchema := range s.AllOf {
 		if subSchema == nil {
 			return validationError{Path: fmt.Sprintf("%s.allOf[%d]", path, i), Message: "schema is null"}
@@ -306,6 +502,61 @@ func validateSchema(path string, s *apitypes.Schema, componentNames map[string]s
 		}
 	}
 
+	for _, pattern := range apitypes.SortedKeys(s.PatternProperties) {
+		subSchema := s.PatternProperties[pattern]
+		if pattern == "" {
+			return validationError{Path: path + ".patternProperties", Message: "pattern is empty"}
+		}
+		if subSchema == nil {
+			return validationError{Path: fmt.Sprintf("%s.patternProperties[%q]", path, pattern), Message: "schema is null"}
+		}
+		if err := validateSchema(fmt.Sprintf("%s.patternProperties[%q]", path, pattern), subSchema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	if s.PropertyNames != nil {
+		for _, schemaType := range s.PropertyNames.Type.Values {
+			if schemaType != "string" {
+				return validationError{Path: path + ".propertyNames.type", Message: fmt.Sprintf("property names must be strings, got %q", schemaType)}
+			}
+		}
+		if err := validateSchema(path+".propertyNames", s.PropertyNames, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	if s.UnevaluatedProperties != nil && s.UnevaluatedProperties.Schema != nil {
+		if err := validateSchema(path+".unevaluatedProperties", s.UnevaluatedProperties.Schema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	if s.If == nil && (s.Then != nil || s.Else != nil) {
+		return validationError{Path: path, Message: "then/else requires if"}
+	}
+	for _, conditional := range []struct {
+		keyword string
+		schema  *apitypes.Schema
+	}{{"if", s.If}, {"then", s.Then}, {"else", s.Else}, {"not", s.Not}} {
+		if conditional.schema == nil {
+			continue
+		}
+		if err := validateSchema(path+"."+conditional.keyword, conditional.schema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range apitypes.SortedKeys(s.Defs) {
+		subSchema := s.Defs[name]
+		if subSchema == nil {
+			return validationError{Path: fmt.Sprintf("%s.$defs[%q]", path, name), Message: "schema is null"}
+		}
+		if err := validateSchema(fmt.Sprintf("%s.$defs[%q]", path, name), subSchema, componentNames, seen); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -334,11 +585,10 @@ func extractPathTemplateParams(path string) ([]string, error) {
 }
 
 func componentSchemaRefName(ref string) (string, bool) {
-	const prefix = "#/components/schemas/"
-	if !strings.HasPrefix(ref, prefix) {
+	if !strings.HasPrefix(ref, componentSchemaRefPrefix) {
 		return "", false
 	}
-	name := strings.TrimPrefix(ref, prefix)
+	name := strings.TrimPrefix(ref, componentSchemaRefPrefix)
 	if name == "" || strings.Contains(name, "/") {
 		return "", false
 	}
@@ -208,3 +208,581 @@ func TestShouldRejectMissingResponsesGivenOperationWithoutResponsesWhenParsingTh
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "missing responses")
 }
+
+func TestShouldDecodeTupleKeywordsGivenPrefixItemsAndFalseItemsWhenParsingThenPopulateSchema(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Coordinates:",
+		"      type: array",
+		"      prefixItems:",
+		"        - type: number",
+		"        - type: number",
+		"      items: false",
+		"      contains:",
+		"        type: number",
+	)))
+
+	require.NoError(t, err)
+	schema := api.Components.Schemas["Coordinates"]
+	require.Len(t, schema.PrefixItems, 2)
+	assert.True(t, schema.Items.IsFalse())
+	require.NotNil(t, schema.Contains)
+	assert.True(t, schema.Contains.Type.Has("number"))
+}
+
+func TestShouldRejectInvalidItemBoundsGivenMinItemsAboveMaxItemsWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Pair:",
+		"      type: array",
+		"      minItems: 3",
+		"      maxItems: 2",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "minItems must not exceed maxItems")
+}
+
+func TestShouldRejectNonStringPropertyNamesGivenIntegerPropertyNamesWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Counters:",
+		"      type: object",
+		"      propertyNames:",
+		"        type: integer",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "property names must be strings")
+}
+
+func TestShouldResolveLocalDefsGivenDefsRefWhenParsingThenHoistHelperSchema(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Order:",
+		"      type: object",
+		"      properties:",
+		"        total:",
+		"          $ref: '#/$defs/Money'",
+		"        refund:",
+		"          $ref: '#/components/schemas/Order/$defs/Money'",
+		"      $defs:",
+		"        Money:",
+		"          type: number",
+		"    Money:",
+		"      type: string",
+	)))
+
+	require.NoError(t, err)
+	order := api.Components.Schemas["Order"]
+	assert.Equal(t, "#/components/schemas/OrderMoney", order.Properties["total"].Ref)
+	assert.Equal(t, "#/components/schemas/OrderMoney", order.Properties["refund"].Ref)
+	assert.Same(t, order.Defs["Money"], api.Components.Schemas["OrderMoney"])
+}
+
+func TestShouldKeepOwnerPrefixGivenTakenOwnerHelperNameWhenParsingThenAppendNumericSuffix(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Order:",
+		"      type: object",
+		"      properties:",
+		"        total:",
+		"          $ref: '#/$defs/Money'",
+		"      $defs:",
+		"        Money:",
+		"          type: number",
+		"    Money:",
+		"      type: string",
+		"    OrderMoney:",
+		"      type: integer",
+	)))
+
+	require.NoError(t, err)
+	order := api.Components.Schemas["Order"]
+	assert.Equal(t, "#/components/schemas/OrderMoney2", order.Properties["total"].Ref)
+	assert.Same(t, order.Defs["Money"], api.Components.Schemas["OrderMoney2"])
+}
+
+func TestShouldRejectUnresolvedLocalRefGivenMissingDefWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Order:",
+		"      type: object",
+		"      properties:",
+		"        total:",
+		"          $ref: '#/$defs/Missing'",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `components.schemas["Order"].$ref: unresolved ref "#/$defs/Missing"`)
+}
+
+func TestShouldRejectUnknownAnchorGivenAnchorRefWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Order:",
+		"      type: object",
+		"      properties:",
+		"        total:",
+		"          $ref: '#amount'",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `no schema declares $anchor "amount"`)
+}
+
+func TestShouldRejectThenWithoutIfGivenConditionalSchemaWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  schemas:",
+		"    Payment:",
+		"      type: object",
+		"      then:",
+		"        required: [card]",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "then/else requires if")
+}
+
+func TestShouldRejectUnknownEncodingStyleGivenFormBodyWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /oauth/token:",
+		"    post:",
+		"      operationId: issueToken",
+		"      requestBody:",
+		"        content:",
+		"          application/x-www-form-urlencoded:",
+		"            schema:",
+		"              type: object",
+		"              properties:",
+		"                scope:",
+		"                  type: array",
+		"                  items:",
+		"                    type: string",
+		"            encoding:",
+		"              scope:",
+		"                style: commaDelimited",
+		"      responses:",
+		"        '200':",
+		"          description: ok",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `unsupported encoding style "commaDelimited"`)
+	assert.ErrorContains(t, err, `encoding["scope"].style`)
+}
+
+func TestShouldAcceptItemSchemaGivenStreamingResponseWithoutSchemaWhenParsingThenDecodeItemSchema(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /events:",
+		"    get:",
+		"      operationId: streamEvents",
+		"      responses:",
+		"        '200':",
+		"          description: ok",
+		"          content:",
+		"            text/event-stream:",
+		"              itemSchema:",
+		"                type: object",
+		"                properties:",
+		"                  id:",
+		"                    type: string",
+	)))
+
+	require.NoError(t, err)
+	media := api.Paths["/events"]["get"].Responses["200"].Content["text/event-stream"]
+	require.NotNil(t, media.StreamItemSchema())
+	assert.Contains(t, media.StreamItemSchema().Properties, "id")
+}
+
+func TestShouldReportFirstConditionalErrorGivenInvalidIfAndNotWhenParsingThenFailOnIfEveryTime(t *testing.T) {
+	content := []byte(doc(
+		"components:",
+		"  schemas:",
+		"    Payment:",
+		"      type: object",
+		"      if:",
+		"        $ref: '#/components/schemas/MissingIf'",
+		"      then:",
+		"        $ref: '#/components/schemas/MissingThen'",
+		"      else:",
+		"        required: [iban]",
+		"      not:",
+		"        $ref: '#/components/schemas/MissingNot'",
+	))
+
+	for range 20 {
+		_, err := parser.ParseDocument("openapi.yaml", content)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "MissingIf")
+	}
+}
+
+func TestShouldRejectUnknownReusableHeaderGivenUnresolvedRefWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /orders:",
+		"    get:",
+		"      operationId: listOrders",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"          headers:",
+		"            X-Total-Count:",
+		"              $ref: '#/components/headers/Missing'",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `paths["/orders"]["get"].responses["200"].headers["X-Total-Count"]: unresolved header ref`)
+}
+
+func TestShouldDecodeResponseHeadersGivenReusableHeaderWhenParsingThenResolveSchema(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /orders:",
+		"    get:",
+		"      operationId: listOrders",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"          headers:",
+		"            X-Total-Count:",
+		"              $ref: '#/components/headers/TotalCount'",
+		"            ETag:",
+		"              schema:",
+		"                type: string",
+		"components:",
+		"  headers:",
+		"    TotalCount:",
+		"      required: true",
+		"      schema:",
+		"        type: integer",
+	)))
+
+	require.NoError(t, err)
+	headers := api.Paths["/orders"]["get"].Responses["200"].Headers
+	assert.Equal(t, "#/components/headers/TotalCount", headers["X-Total-Count"].Ref)
+	assert.True(t, api.Components.Headers["TotalCount"].Required)
+	assert.True(t, headers["ETag"].Schema.Type.Has("string"))
+}
+
+func TestShouldRejectUnknownSecuritySchemeGivenOperationRequirementWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /accounts:",
+		"    get:",
+		"      operationId: listAccounts",
+		"      security:",
+		"        - missingAuth: []",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `paths["/accounts"]["get"].security[0]["missingAuth"]: unknown security scheme "missingAuth"`)
+}
+
+func TestShouldRejectUnsupportedApiKeyLocationGivenSecuritySchemeWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths: {}",
+		"components:",
+		"  securitySchemes:",
+		"    apiKey:",
+		"      type: apiKey",
+		"      name: X-API-Key",
+		"      in: body",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `components.securitySchemes["apiKey"].in: unsupported apiKey location "body"`)
+}
+
+func TestShouldDistinguishPublicOperationsGivenEmptySecurityWhenParsingThenKeepEmptyRequirements(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"security:",
+		"  - bearerAuth: []",
+		"paths:",
+		"  /health:",
+		"    get:",
+		"      operationId: getHealth",
+		"      security: []",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"  /accounts:",
+		"    get:",
+		"      operationId: listAccounts",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"components:",
+		"  securitySchemes:",
+		"    bearerAuth:",
+		"      type: http",
+		"      scheme: bearer",
+	)))
+
+	require.NoError(t, err)
+	require.NotNil(t, api.Paths["/health"]["get"].Security)
+	assert.Empty(t, *api.Paths["/health"]["get"].Security)
+	assert.Nil(t, api.Paths["/accounts"]["get"].Security)
+	require.Len(t, api.Security, 1)
+	assert.Contains(t, api.Security[0], "bearerAuth")
+}
+
+func TestShouldRejectUndeclaredServerVariableGivenTemplatedServerURLWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"servers:",
+		"  - url: https://{region}.api.example.com",
+		"paths: {}",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `servers[0].url: undeclared server variable "region"`)
+}
+
+func TestShouldRejectServerVariableDefaultGivenValueOutsideEnumWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"servers:",
+		"  - url: https://{region}.api.example.com",
+		"    variables:",
+		"      region:",
+		"        default: ap",
+		"        enum: [us, eu]",
+		"paths: {}",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `servers[0].variables["region"].default: default "ap" is not one of the enum values`)
+}
+
+func TestShouldDecodePathItemServersGivenPathAndOperationServersWhenParsingThenKeepOverrides(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /uploads:",
+		"    servers:",
+		"      - url: https://uploads.example.com",
+		"    summary: Uploads",
+		"    post:",
+		"      operationId: uploadFile",
+		"      servers:",
+		"        - url: https://{region}.uploads.example.com",
+		"          variables:",
+		"            region:",
+		"              default: us",
+		"      responses:",
+		"        \"201\":",
+		"          description: created",
+	)))
+
+	require.NoError(t, err)
+	require.Len(t, api.PathServers["/uploads"], 1)
+	assert.Equal(t, "https://uploads.example.com", api.PathServers["/uploads"][0].URL)
+	require.Len(t, api.Paths["/uploads"], 1)
+	assert.Equal(t, "https://{region}.uploads.example.com", api.Paths["/uploads"]["post"].Servers[0].URL)
+}
+
+func TestShouldDecodePathItemServersGivenJSONDocumentWhenParsingThenKeepOverrides(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.json", []byte(`{
+		"paths": {
+			"/uploads": {
+				"servers": [{ "url": "https://uploads.example.com" }],
+				"post": { "operationId": "uploadFile", "responses": { "201": { "description": "created" } } }
+			}
+		}
+	}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://uploads.example.com", api.PathServers["/uploads"][0].URL)
+	assert.Equal(t, "uploadFile", api.Paths["/uploads"]["post"].OperationID)
+}
+
+func TestShouldRejectUndeclaredServerVariableGivenOperationServerWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /uploads:",
+		"    post:",
+		"      operationId: uploadFile",
+		"      servers:",
+		"        - url: https://{region}.uploads.example.com",
+		"      responses:",
+		"        \"201\":",
+		"          description: created",
+	)))
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `paths["/uploads"]["post"].servers[0].url: undeclared server variable "region"`)
+}
+
+func TestShouldMergePathParametersGivenPathLevelParametersWhenParsingThenLetOperationsOverrideThem(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /items/{id}:",
+		"    parameters:",
+		"      - name: id",
+		"        in: path",
+		"        required: true",
+		"        schema:",
+		"          type: string",
+		"      - name: tenant",
+		"        in: query",
+		"        required: true",
+		"        schema:",
+		"          type: string",
+		"    get:",
+		"      operationId: getItem",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+		"    delete:",
+		"      operationId: deleteItem",
+		"      parameters:",
+		"        - name: tenant",
+		"          in: query",
+		"          description: Owning tenant",
+		"          schema:",
+		"            type: string",
+		"      responses:",
+		"        \"204\":",
+		"          description: deleted",
+	)))
+
+	require.NoError(t, err)
+	get := api.Paths["/items/{id}"]["get"]
+	require.Len(t, get.Parameters, 2)
+	assert.Equal(t, "id", get.Parameters[0].Name)
+	assert.Equal(t, "tenant", get.Parameters[1].Name)
+	assert.True(t, get.Parameters[1].Required)
+	del := api.Paths["/items/{id}"]["delete"]
+	require.Len(t, del.Parameters, 2)
+	assert.Equal(t, "id", del.Parameters[0].Name)
+	assert.Equal(t, "Owning tenant", del.Parameters[1].Description)
+	assert.False(t, del.Parameters[1].Required)
+}
+
+func TestShouldRejectUnsupportedPathItemFieldGivenPathItemRefWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /items:",
+		"    $ref: '#/components/pathItems/Items'",
+		"    get:",
+		"      operationId: listItems",
+		"      responses:",
+		"        \"200\":",
+		"          description: ok",
+	)))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `paths["/items"]: unsupported path item field "$ref"`)
+}
+
+func TestShouldRejectUnsupportedPathItemFieldGivenJSONDocumentWhenParsingThenReturnError(t *testing.T) {
+	_, err := parser.ParseDocument("openapi.json", []byte(`{"paths": {"/items": {"gett": {"operationId": "listItems"}}}}`))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported path item field "gett"`)
+}
+
+func TestShouldDecodeDeprecatedGivenOperationParameterAndSchemaWhenParsingThenMarkThem(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /users:",
+		"    get:",
+		"      operationId: listUsers",
+		"      deprecated: true",
+		"      parameters:",
+		"        - name: sort",
+		"          in: query",
+		"          deprecated: true",
+		"          schema:",
+		"            type: string",
+		"      responses:",
+		"        \"200\":",
+		"          description: users",
+		"          content:",
+		"            application/json:",
+		"              schema:",
+		"                type: object",
+		"                properties:",
+		"                  login:",
+		"                    type: string",
+		"                    deprecated: true",
+	)))
+
+	require.NoError(t, err)
+	op := api.Paths["/users"]["get"]
+	assert.True(t, op.Deprecated)
+	assert.True(t, op.Parameters[0].Deprecated)
+	assert.True(t, op.Responses["200"].Content["application/json"].Schema.Properties["login"].Deprecated)
+}
+
+func TestShouldPreserveExtensionsGivenXFieldsWhenParsingThenKeepThemOnOperationsAndSchemas(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.yaml", []byte(doc(
+		"paths:",
+		"  /admin:",
+		"    get:",
+		"      operationId: getAdmin",
+		"      x-internal: true",
+		"      x-owner: { team: platform }",
+		"      responses:",
+		"        \"200\":",
+		"          description: admin",
+		"          content:",
+		"            application/json:",
+		"              schema:",
+		"                type: object",
+		"                x-fetch-gen-ignore: true",
+		"                properties:",
+		"                  secret:",
+		"                    type: string",
+		"                    x-internal: true",
+	)))
+
+	require.NoError(t, err)
+	op := api.Paths["/admin"]["get"]
+	assert.True(t, op.Extensions.Bool("x-internal"))
+	assert.Equal(t, map[string]any{"team": "platform"}, op.Extensions["x-owner"])
+	schema := op.Responses["200"].Content["application/json"].Schema
+	assert.True(t, schema.Extensions.Bool("x-fetch-gen-ignore"))
+	assert.True(t, schema.Properties["secret"].Extensions.Bool("x-internal"))
+	assert.Nil(t, schema.Properties["secret"].Extensions["x-fetch-gen-ignore"])
+}
+
+func TestShouldPreserveExtensionsGivenJSONDocumentWhenParsingThenKeepThemOnOperationsAndSchemas(t *testing.T) {
+	api, err := parser.ParseDocument("openapi.json", []byte(`{
+		"openapi": "3.1.0",
+		"info": {"title": "Test", "version": "1.0.0"},
+		"paths": {"/admin": {"get": {
+			"operationId": "getAdmin",
+			"x-internal": true,
+			"responses": {"200": {"description": "admin", "content": {"application/json": {"schema": {
+				"type": "object",
+				"properties": {"secret": {"type": "string", "x-internal": true}}
+			}}}}}
+		}}}
+	}`))
+
+	require.NoError(t, err)
+	op := api.Paths["/admin"]["get"]
+	assert.True(t, op.Extensions.Bool("x-internal"))
+	assert.True(t, op.Responses["200"].Content["application/json"].Schema.Properties["secret"].Extensions.Bool("x-internal"))
+}
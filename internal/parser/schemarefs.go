@@ -0,0 +1,297 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	apitypes "github.com/fgrzl/fetch-gen/internal/types"
+)
+
+const componentSchemaRefPrefix = "#/components/schemas/"
+
+// schemaResource is a JSON Schema resource: a component schema or any schema carrying $id.
+// Fragment-only refs such as '#/$defs/Foo' or '#street' resolve against the enclosing resource.
+type schemaResource struct {
+	root    *apitypes.Schema
+	base    *url.URL
+	anchors map[string]*apitypes.Schema
+}
+
+type helperHint struct {
+	name  string
+	owner string
+}
+
+type schemaRefResolver struct {
+	api        *apitypes.OpenAPI
+	resources  map[string]*schemaResource
+	components map[string]*schemaResource
+	resourceOf map[*apitypes.Schema]*schemaResource
+	pathOf     map[*apitypes.Schema]string
+	order      []*apitypes.Schema
+	hints      map[*apitypes.Schema]helperHint
+	names      map[*apitypes.Schema]string
+}
+
+// resolveSchemaRefs rewrites $defs, $anchor and $id based refs into component refs. Each target that is
+// not already a component is hoisted into components.schemas under a helper name so it can be emitted
+// as a named type.
+func resolveSchemaRefs(api *apitypes.OpenAPI) error {
+	r := &schemaRefResolver{
+		api:        api,
+		resources:  map[string]*schemaResource{},
+		components: map[string]*schemaResource{},
+		resourceOf: map[*apitypes.Schema]*schemaResource{},
+		pathOf:     map[*apitypes.Schema]string{},
+		hints:      map[*apitypes.Schema]helperHint{},
+		names:      map[*apitypes.Schema]string{},
+	}
+
+	for _, name := range apitypes.SortedKeys(api.Components.Schemas) {
+		schema := api.Components.Schemas[name]
+		if schema == nil {
+			continue
+		}
+		r.names[schema] = name
+		resource := r.newResource(schema, nil)
+		r.components[name] = resource
+		r.index(schema, resource, name, fmt.Sprintf("components.schemas[%q]", name))
+	}
+
+	for _, name := range apitypes.SortedKeys(api.Components.Parameters) {
+		if param := api.Components.Parameters[name]; param != nil && param.Schema != nil {
+			r.index(param.Schema, nil, "", fmt.Sprintf("components.parameters[%q].schema", name))
+		}
+	}
+
+	for _, name := range apitypes.SortedKeys(api.Components.Headers) {
+		if header := api.Components.Headers[name]; header != nil && header.Schema != nil {
+			r.index(header.Schema, nil, "", fmt.Sprintf("components.headers[%q].schema", name))
+		}
+	}
+
+	for op := range api.Operations() {
+		for schema := range op.Operation.Schemas() {
+			r.index(schema.Schema, nil, "", op.Location()+"."+schema.Location)
+		}
+	}
+
+	for _, schema := range r.order {
+		if schema.Ref == "" {
+			continue
+		}
+		target, err := r.lookup(schema)
+		if err != nil {
+			return validationError{Path: r.pathOf[schema] + ".$ref", Message: err.Error()}
+		}
+		if target == nil {
+			continue
+		}
+		schema.Ref = componentSchemaRefPrefix + r.helperName(target)
+	}
+
+	return nil
+}
+
+func (r *schemaRefResolver) newResource(root *apitypes.Schema, parentBase *url.URL) *schemaResource {
+	resource := &schemaResource{root: root, base: parentBase, anchors: map[string]*apitypes.Schema{}}
+	if root.ID == "" {
+		return resource
+	}
+	id, err := url.Parse(root.ID)
+	if err != nil {
+		return resource
+	}
+	if parentBase != nil {
+		id = parentBase.ResolveReference(id)
+	}
+	id.Fragment = ""
+	resource.base = id
+	r.resources[id.String()] = resource
+	return resource
+}
+
+func (r *schemaRefResolver) index(s *apitypes.Schema, resource *schemaResource, owner string, path string) {
+	if s == nil {
+		return
+	}
+	if _, ok := r.resourceOf[s]; ok {
+		return
+	}
+
+	if s.ID != "" && (resource == nil || resource.root != s) {
+		var parentBase *url.URL
+		if resource != nil {
+			parentBase = resource.base
+		}
+		resource = r.newResource(s, parentBase)
+		if _, ok := r.hints[s]; !ok {
+			r.hints[s] = helperHint{name: idHelperName(s.ID), owner: owner}
+		}
+	}
+	if resource == nil {
+		resource = &schemaResource{anchors: map[string]*apitypes.Schema{}}
+	}
+	if s.Anchor != "" {
+		resource.anchors[s.Anchor] = s
+		if _, ok := r.hints[s]; !ok {
+			r.hints[s] = helperHint{name: s.Anchor, owner: owner}
+		}
+	}
+	for _, key := range apitypes.SortedKeys(s.Defs) {
+		if def := s.Defs[key]; def != nil {
+			if _, ok := r.hints[def]; !ok {
+				r.hints[def] = helperHint{name: key, owner: owner}
+			}
+		}
+	}
+
+	r.resourceOf[s] = resource
+	r.pathOf[s] = path
+	r.order = append(r.order, s)
+
+	for _, sub := range s.Subschemas() {
+		r.index(sub, resource, owner, path)
+	}
+}
+
+// lookup returns the schema a ref points at, or nil when the ref is already a plain component ref.
+func (r *schemaRefResolver) lookup(s *apitypes.Schema) (*apitypes.Schema, error) {
+	ref := s.Ref
+	if strings.HasPrefix(ref, componentSchemaRefPrefix) {
+		name, pointer, hasPointer := strings.Cut(strings.TrimPrefix(ref, componentSchemaRefPrefix), "/")
+		if !hasPointer {
+			return nil, nil
+		}
+		component, ok := r.components[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved ref %q", ref)
+		}
+		return resolveSchemaPointer(component.root, "/"+pointer, ref)
+	}
+	if strings.HasPrefix(ref, "#/components/") {
+		return nil, nil
+	}
+
+	uriPart, fragment, _ := strings.Cut(ref, "#")
+	resource := r.resourceOf[s]
+	if uriPart != "" {
+		target, err := url.Parse(uriPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ref %q", ref)
+		}
+		if resource != nil && resource.base != nil {
+			target = resource.base.ResolveReference(target)
+		}
+		var ok bool
+		resource, ok = r.resources[target.String()]
+		if !ok {
+			return nil, fmt.Errorf("unresolved ref %q: no schema declares $id %q", ref, target.String())
+		}
+	}
+	if resource == nil || resource.root == nil {
+		return nil, fmt.Errorf("unresolved ref %q: no enclosing schema resource", ref)
+	}
+
+	switch {
+	case fragment == "":
+		return resource.root, nil
+	case strings.HasPrefix(fragment, "/"):
+		return resolveSchemaPointer(resource.root, fragment, ref)
+	default:
+		anchored, ok := resource.anchors[fragment]
+		if !ok {
+			return nil, fmt.Errorf("unresolved ref %q: no schema declares $anchor %q", ref, fragment)
+		}
+		return anchored, nil
+	}
+}
+
+func resolveSchemaPointer(root *apitypes.Schema, pointer string, ref string) (*apitypes.Schema, error) {
+	current := root
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i := 0; i < len(segments); i++ {
+		if current == nil {
+			break
+		}
+		keyword := unescapePointerSegment(segments[i])
+		switch keyword {
+		case "$defs", "properties":
+			if i+1 >= len(segments) {
+				return nil, fmt.Errorf("unresolved ref %q", ref)
+			}
+			i++
+			name := unescapePointerSegment(segments[i])
+			if keyword == "$defs" {
+				current = current.Defs[name]
+			} else {
+				current = current.Properties[name]
+			}
+		case "items":
+			if current.Items == nil {
+				current = nil
+			} else {
+				current = current.Items.Schema
+			}
+		default:
+			return nil, fmt.Errorf("unsupported ref %q", ref)
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("unresolved ref %q", ref)
+	}
+	return current, nil
+}
+
+func unescapePointerSegment(segment string) string {
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
+
+func (r *schemaRefResolver) helperName(target *apitypes.Schema) string {
+	if name, ok := r.names[target]; ok {
+		return name
+	}
+
+	hint := r.hints[target]
+	base := apitypes.PascalCase(hint.name)
+	if base == "" {
+		base = "Schema"
+	}
+	if _, taken := r.api.Components.Schemas[base]; taken && hint.owner != "" {
+		base = apitypes.PascalCase(hint.owner) + base
+	}
+	if unicode.IsDigit([]rune(base)[0]) {
+		base = "_" + base
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		if _, taken := r.api.Components.Schemas[candidate]; !taken {
+			break
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+
+	if r.api.Components.Schemas == nil {
+		r.api.Components.Schemas = map[string]*apitypes.Schema{}
+	}
+	r.api.Components.Schemas[candidate] = target
+	r.names[target] = candidate
+	return candidate
+}
+
+func idHelperName(id string) string {
+	trimmed := strings.TrimRight(strings.SplitN(id, "#", 2)[0], "/")
+	if slash := strings.LastIndex(trimmed, "/"); slash >= 0 {
+		trimmed = trimmed[slash+1:]
+	}
+	if dot := strings.Index(trimmed, "."); dot > 0 {
+		trimmed = trimmed[:dot]
+	}
+	return trimmed
+}
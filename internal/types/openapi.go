@@ -3,16 +3,75 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// AdditionalProperties can be either boolean or Schema.
-type AdditionalProperties struct {
+// Extensions holds the specification extensions (x-* fields) of an object, keyed by field name.
+type Extensions map[string]any
+
+// Bool reports whether the extension name is set to true.
+func (e Extensions) Bool(name string) bool {
+	value, _ := e[name].(bool)
+	return value
+}
+
+func decodeExtensionsYAML(node *yaml.Node) (Extensions, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var extensions Extensions
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if !strings.HasPrefix(key, "x-") {
+			continue
+		}
+		var value any
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if extensions == nil {
+			extensions = Extensions{}
+		}
+		extensions[key] = value
+	}
+	return extensions, nil
+}
+
+func decodeExtensionsJSON(data []byte) (Extensions, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var extensions Extensions
+	for key, raw := range fields {
+		if !strings.HasPrefix(key, "x-") {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if extensions == nil {
+			extensions = Extensions{}
+		}
+		extensions[key] = value
+	}
+	return extensions, nil
+}
+
+// BooleanSchema is a JSON Schema slot that can be either boolean or Schema,
+// such as additionalProperties or (in OpenAPI 3.1) items.
+type BooleanSchema struct {
 	Boolean *bool
 	Schema  *Schema
 }
 
+// AdditionalProperties can be either boolean or Schema.
+type AdditionalProperties = BooleanSchema
+
 // SchemaType supports OpenAPI 3.1 / JSON Schema where type can be a string or an array of strings.
 // Examples: "string" or ["string", "null"].
 type SchemaType struct {
@@ -82,10 +141,10 @@ func (st SchemaType) IsEmpty() bool {
 	return len(st.Values) == 0
 }
 
-func (ap *AdditionalProperties) UnmarshalYAML(node *yaml.Node) error {
+func (bs *BooleanSchema) UnmarshalYAML(node *yaml.Node) error {
 	var b bool
 	if err := node.Decode(&b); err == nil {
-		ap.Boolean = &b
+		bs.Boolean = &b
 		return nil
 	}
 
@@ -93,14 +152,14 @@ func (ap *AdditionalProperties) UnmarshalYAML(node *yaml.Node) error {
 	if err := node.Decode(&s); err != nil {
 		return err
 	}
-	ap.Schema = &s
+	bs.Schema = &s
 	return nil
 }
 
-func (ap *AdditionalProperties) UnmarshalJSON(data []byte) error {
+func (bs *BooleanSchema) UnmarshalJSON(data []byte) error {
 	var b bool
 	if err := json.Unmarshal(data, &b); err == nil {
-		ap.Boolean = &b
+		bs.Boolean = &b
 		return nil
 	}
 
@@ -108,32 +167,243 @@ func (ap *AdditionalProperties) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
-	ap.Schema = &s
+	bs.Schema = &s
 	return nil
 }
 
+// IsFalse reports whether the slot is the boolean schema `false`, which forbids any value.
+func (bs *BooleanSchema) IsFalse() bool {
+	return bs != nil && bs.Boolean != nil && !*bs.Boolean
+}
+
 type OpenAPI struct {
 	Paths      map[string]map[string]*Operation `json:"paths" yaml:"paths"`
 	Components Components                       `json:"components" yaml:"components"`
 	Servers    []Server                         `json:"servers" yaml:"servers"`
+	Security   []SecurityRequirement            `json:"security" yaml:"security"`
+	// PathServers are the servers declared on path items, keyed by path; they override Servers for
+	// every operation of the path that does not declare its own.
+	PathServers map[string][]Server `json:"-" yaml:"-"`
+}
+
+// pathItemMethods are the path item keys that hold operations.
+var pathItemMethods = map[string]struct{}{
+	"get": {}, "put": {}, "post": {}, "delete": {}, "options": {}, "head": {}, "patch": {}, "trace": {},
+}
+
+// pathItemDocs are the path item keys that only document the path.
+var pathItemDocs = map[string]struct{}{
+	"summary": {}, "description": {},
+}
+
+// UnmarshalYAML decodes path items key by key: methods become operations, `servers` becomes
+// PathServers and `parameters` are merged into every operation of the path. `summary`, `description`
+// and x-* fields are ignored; any other field is rejected.
+func (api *OpenAPI) UnmarshalYAML(node *yaml.Node) error {
+	type plain OpenAPI
+	if node.Kind != yaml.MappingNode {
+		return node.Decode((*plain)(api))
+	}
+	rest := *node
+	rest.Content = nil
+	var paths *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "paths" {
+			paths = node.Content[i+1]
+			continue
+		}
+		rest.Content = append(rest.Content, node.Content[i], node.Content[i+1])
+	}
+	if err := rest.Decode((*plain)(api)); err != nil {
+		return err
+	}
+	if paths == nil {
+		return nil
+	}
+	var items map[string]map[string]yaml.Node
+	if err := paths.Decode(&items); err != nil {
+		return err
+	}
+	return decodePathItems(api, items, func(value yaml.Node, target any) error { return value.Decode(target) })
+}
+
+// UnmarshalJSON decodes path items the same way as UnmarshalYAML.
+func (api *OpenAPI) UnmarshalJSON(data []byte) error {
+	type plain OpenAPI
+	doc := struct {
+		*plain
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{plain: (*plain)(api)}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return decodePathItems(api, doc.Paths, func(value json.RawMessage, target any) error { return json.Unmarshal(value, target) })
+}
+
+func decodePathItems[V any](api *OpenAPI, items map[string]map[string]V, decode func(value V, target any) error) error {
+	if items == nil {
+		return nil
+	}
+	api.Paths = make(map[string]map[string]*Operation, len(items))
+	for path, item := range items {
+		operations := map[string]*Operation{}
+		var parameters []*Parameter
+		keys := make([]string, 0, len(item))
+		for key := range item {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := item[key]
+			if _, ok := pathItemDocs[key]; ok || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			if key == "parameters" {
+				if err := decode(value, &parameters); err != nil {
+					return fmt.Errorf("paths[%q].parameters: %w", path, err)
+				}
+				continue
+			}
+			if key == "servers" {
+				var servers []Server
+				if err := decode(value, &servers); err != nil {
+					return fmt.Errorf("paths[%q].servers: %w", path, err)
+				}
+				if api.PathServers == nil {
+					api.PathServers = map[string][]Server{}
+				}
+				api.PathServers[path] = servers
+				continue
+			}
+			if _, ok := pathItemMethods[key]; !ok {
+				return fmt.Errorf("paths[%q]: unsupported path item field %q", path, key)
+			}
+			var op *Operation
+			if err := decode(value, &op); err != nil {
+				return fmt.Errorf("paths[%q][%q]: %w", path, key, err)
+			}
+			operations[key] = op
+		}
+		for _, op := range operations {
+			if op != nil && len(parameters) > 0 {
+				op.Parameters = mergeParameters(api.Components.Parameters, parameters, op.Parameters)
+			}
+		}
+		api.Paths[path] = operations
+	}
+	return nil
+}
+
+// mergeParameters returns the path-level parameters an operation does not override, followed by the
+// operation's own; a parameter is overridden by one with the same name and location.
+func mergeParameters(components map[string]*Parameter, pathParameters []*Parameter, opParameters []*Parameter) []*Parameter {
+	type parameterKey struct{ name, in string }
+	keyOf := func(p *Parameter) parameterKey {
+		seen := map[string]struct{}{}
+		for p != nil && p.Ref != "" {
+			if _, ok := seen[p.Ref]; ok {
+				break
+			}
+			seen[p.Ref] = struct{}{}
+			target, ok := components[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return parameterKey{name: p.Ref}
+			}
+			p = target
+		}
+		if p == nil {
+			return parameterKey{}
+		}
+		return parameterKey{name: p.Name, in: p.In}
+	}
+	overridden := map[parameterKey]struct{}{}
+	for _, p := range opParameters {
+		overridden[keyOf(p)] = struct{}{}
+	}
+	merged := make([]*Parameter, 0, len(pathParameters)+len(opParameters))
+	for _, p := range pathParameters {
+		if _, ok := overridden[keyOf(p)]; !ok {
+			merged = append(merged, p)
+		}
+	}
+	return append(merged, opParameters...)
 }
 
 type Server struct {
-	URL string `json:"url" yaml:"url"`
+	URL         string                     `json:"url" yaml:"url"`
+	Description string                     `json:"description" yaml:"description"`
+	Variables   map[string]*ServerVariable `json:"variables" yaml:"variables"`
+}
+
+// ServerVariable is a `{name}` placeholder of a server URL, substituted with Default unless the client
+// picks another value (one of Enum, when given).
+type ServerVariable struct {
+	Enum        []string `json:"enum" yaml:"enum"`
+	Default     string   `json:"default" yaml:"default"`
+	Description string   `json:"description" yaml:"description"`
 }
 
 type Components struct {
 	Schemas    map[string]*Schema    `json:"schemas" yaml:"schemas"`
 	Parameters map[string]*Parameter `json:"parameters" yaml:"parameters"`
+	Headers    map[string]*Header    `json:"headers" yaml:"headers"`
+	// SecuritySchemes are the schemes security requirements refer to by name.
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes" yaml:"securitySchemes"`
+}
+
+// SecurityScheme describes how a client authenticates: an API key in a header, query parameter or
+// cookie, an HTTP Authorization scheme such as bearer or basic, OAuth 2, OpenID Connect or mutual TLS.
+type SecurityScheme struct {
+	Type         string `json:"type" yaml:"type"`
+	Description  string `json:"description" yaml:"description"`
+	Name         string `json:"name" yaml:"name"`
+	In           string `json:"in" yaml:"in"`
+	Scheme       string `json:"scheme" yaml:"scheme"`
+	BearerFormat string `json:"bearerFormat" yaml:"bearerFormat"`
 }
 
+// SecurityRequirement maps security scheme names to the scopes required from each; every listed scheme
+// must be satisfied. An empty requirement makes authentication optional.
+type SecurityRequirement map[string][]string
+
 type Operation struct {
 	OperationID string               `json:"operationId" yaml:"operationId"`
+	Tags        []string             `json:"tags" yaml:"tags"`
 	Summary     string               `json:"summary" yaml:"summary"`
 	Description string               `json:"description" yaml:"description"`
 	RequestBody *RequestBodyWrapper  `json:"requestBody" yaml:"requestBody"`
 	Responses   map[string]*Response `json:"responses" yaml:"responses"`
 	Parameters  []*Parameter         `json:"parameters" yaml:"parameters"`
+	// Security overrides the document's security requirements when set; an empty list marks the
+	// operation public.
+	Security *[]SecurityRequirement `json:"security" yaml:"security"`
+	// Servers overrides the path and document servers for this operation.
+	Servers    []Server `json:"servers" yaml:"servers"`
+	Deprecated bool     `json:"deprecated" yaml:"deprecated"`
+	// Extensions are the operation's x-* fields.
+	Extensions Extensions `json:"-" yaml:"-"`
+}
+
+// UnmarshalYAML decodes the operation and keeps its x-* fields in Extensions.
+func (op *Operation) UnmarshalYAML(node *yaml.Node) error {
+	type plain Operation
+	if err := node.Decode((*plain)(op)); err != nil {
+		return err
+	}
+	extensions, err := decodeExtensionsYAML(node)
+	op.Extensions = extensions
+	return err
+}
+
+// UnmarshalJSON decodes the operation and keeps its x-* fields in Extensions.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	type plain Operation
+	if err := json.Unmarshal(data, (*plain)(op)); err != nil {
+		return err
+	}
+	extensions, err := decodeExtensionsJSON(data)
+	op.Extensions = extensions
+	return err
 }
 
 type RequestBodyWrapper struct {
@@ -143,27 +413,138 @@ type RequestBodyWrapper struct {
 
 type MediaType struct {
 	Schema *Schema `json:"schema" yaml:"schema"`
+	// ItemSchema and StreamItem (x-stream-item) describe each item of a sequential media type such as
+	// text/event-stream or application/x-ndjson.
+	ItemSchema *Schema              `json:"itemSchema" yaml:"itemSchema"`
+	StreamItem *Schema              `json:"x-stream-item" yaml:"x-stream-item"`
+	Encoding   map[string]*Encoding `json:"encoding" yaml:"encoding"`
+}
+
+// StreamItemSchema returns the schema of each streamed item, preferring itemSchema over x-stream-item.
+func (m MediaType) StreamItemSchema() *Schema {
+	if m.ItemSchema != nil {
+		return m.ItemSchema
+	}
+	return m.StreamItem
+}
+
+// Encoding describes how one property of a form-urlencoded or multipart body is serialized.
+type Encoding struct {
+	ContentType   string `json:"contentType" yaml:"contentType"`
+	Style         string `json:"style" yaml:"style"`
+	Explode       *bool  `json:"explode" yaml:"explode"`
+	AllowReserved bool   `json:"allowReserved" yaml:"allowReserved"`
 }
 
 type Response struct {
 	Description string               `json:"description" yaml:"description"`
 	Content     map[string]MediaType `json:"content" yaml:"content"`
+	Headers     map[string]*Header   `json:"headers" yaml:"headers"`
 }
 
 type Schema struct {
-	Type                 SchemaType            `json:"type" yaml:"type"`
-	Format               string                `json:"format" yaml:"format"`
-	Properties           map[string]*Schema    `json:"properties" yaml:"properties"`
-	Items                *Schema               `json:"items" yaml:"items"`
-	Enum                 []any                 `json:"enum" yaml:"enum"`
-	Ref                  string                `json:"$ref" yaml:"$ref"`
-	Description          string                `json:"description" yaml:"description"`
-	Required             []string              `json:"required" yaml:"required"`
-	Nullable             *bool                 `json:"nullable" yaml:"nullable"`
-	AllOf                []*Schema             `json:"allOf" yaml:"allOf"`
-	OneOf                []*Schema             `json:"oneOf" yaml:"oneOf"`
-	AnyOf                []*Schema             `json:"anyOf" yaml:"anyOf"`
-	AdditionalProperties *AdditionalProperties `json:"additionalProperties" yaml:"additionalProperties"`
+	Type                  SchemaType            `json:"type" yaml:"type"`
+	Format                string                `json:"format" yaml:"format"`
+	Properties            map[string]*Schema    `json:"properties" yaml:"properties"`
+	Items                 *BooleanSchema        `json:"items" yaml:"items"`
+	PrefixItems           []*Schema             `json:"prefixItems" yaml:"prefixItems"`
+	Contains              *Schema               `json:"contains" yaml:"contains"`
+	MinItems              *int                  `json:"minItems" yaml:"minItems"`
+	MaxItems              *int                  `json:"maxItems" yaml:"maxItems"`
+	Enum                  []any                 `json:"enum" yaml:"enum"`
+	Ref                   string                `json:"$ref" yaml:"$ref"`
+	Description           string                `json:"description" yaml:"description"`
+	Required              []string              `json:"required" yaml:"required"`
+	Nullable              *bool                 `json:"nullable" yaml:"nullable"`
+	AllOf                 []*Schema             `json:"allOf" yaml:"allOf"`
+	OneOf                 []*Schema             `json:"oneOf" yaml:"oneOf"`
+	AnyOf                 []*Schema             `json:"anyOf" yaml:"anyOf"`
+	AdditionalProperties  *AdditionalProperties `json:"additionalProperties" yaml:"additionalProperties"`
+	PatternProperties     map[string]*Schema    `json:"patternProperties" yaml:"patternProperties"`
+	PropertyNames         *Schema               `json:"propertyNames" yaml:"propertyNames"`
+	UnevaluatedProperties *BooleanSchema        `json:"unevaluatedProperties" yaml:"unevaluatedProperties"`
+	Pattern               string                `json:"pattern" yaml:"pattern"`
+	ID                    string                `json:"$id" yaml:"$id"`
+	Anchor                string                `json:"$anchor" yaml:"$anchor"`
+	Defs                  map[string]*Schema    `json:"$defs" yaml:"$defs"`
+	If                    *Schema               `json:"if" yaml:"if"`
+	Then                  *Schema               `json:"then" yaml:"then"`
+	Else                  *Schema               `json:"else" yaml:"else"`
+	Not                   *Schema               `json:"not" yaml:"not"`
+	Deprecated            bool                  `json:"deprecated" yaml:"deprecated"`
+	// Extensions are the schema's x-* fields.
+	Extensions Extensions `json:"-" yaml:"-"`
+}
+
+// UnmarshalYAML decodes the schema and keeps its x-* fields in Extensions.
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	type plain Schema
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	extensions, err := decodeExtensionsYAML(node)
+	s.Extensions = extensions
+	return err
+}
+
+// UnmarshalJSON decodes the schema and keeps its x-* fields in Extensions.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	extensions, err := decodeExtensionsJSON(data)
+	s.Extensions = extensions
+	return err
+}
+
+// Subschemas returns the schemas nested directly under s, in a stable order.
+func (s *Schema) Subschemas() []*Schema {
+	if s == nil {
+		return nil
+	}
+
+	var subs []*Schema
+	appendSorted := func(m map[string]*Schema) {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			subs = append(subs, m[key])
+		}
+	}
+
+	appendSorted(s.Properties)
+	if s.Items != nil && s.Items.Schema != nil {
+		subs = append(subs, s.Items.Schema)
+	}
+	subs = append(subs, s.PrefixItems...)
+	if s.Contains != nil {
+		subs = append(subs, s.Contains)
+	}
+	subs = append(subs, s.AllOf...)
+	subs = append(subs, s.OneOf...)
+	subs = append(subs, s.AnyOf...)
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		subs = append(subs, s.AdditionalProperties.Schema)
+	}
+	appendSorted(s.PatternProperties)
+	if s.PropertyNames != nil {
+		subs = append(subs, s.PropertyNames)
+	}
+	if s.UnevaluatedProperties != nil && s.UnevaluatedProperties.Schema != nil {
+		subs = append(subs, s.UnevaluatedProperties.Schema)
+	}
+	for _, sub := range []*Schema{s.If, s.Then, s.Else, s.Not} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	appendSorted(s.Defs)
+
+	return subs
 }
 
 type Parameter struct {
@@ -173,4 +554,13 @@ type Parameter struct {
 	Required    bool    `json:"required" yaml:"required"`
 	Schema      *Schema `json:"schema" yaml:"schema"`
 	Description string  `json:"description" yaml:"description"`
+	Deprecated  bool    `json:"deprecated" yaml:"deprecated"`
+}
+
+// Header is a response header; Ref points at a reusable header under components.headers.
+type Header struct {
+	Ref         string  `json:"$ref" yaml:"$ref"`
+	Description string  `json:"description" yaml:"description"`
+	Required    bool    `json:"required" yaml:"required"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
 }
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PathOperation is an operation with the path and method it is declared under.
+type PathOperation struct {
+	Path      string
+	Method    string
+	Operation *Operation
+}
+
+// Location is the document path of the operation, such as paths["/users"]["get"].
+func (o PathOperation) Location() string {
+	return fmt.Sprintf("paths[%q][%q]", o.Path, o.Method)
+}
+
+// Operations yields the operations of api sorted by path, then method, skipping empty entries.
+func (api *OpenAPI) Operations() iter.Seq[PathOperation] {
+	return func(yield func(PathOperation) bool) {
+		for _, path := range SortedKeys(api.Paths) {
+			for _, method := range SortedKeys(api.Paths[path]) {
+				op := api.Paths[path][method]
+				if op == nil {
+					continue
+				}
+				if !yield(PathOperation{Path: path, Method: method, Operation: op}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SchemaRole is the part of an operation a schema describes.
+type SchemaRole int
+
+const (
+	ParameterSchema SchemaRole = iota
+	RequestBodySchema
+	ResponseHeaderSchema
+	ResponseSchema
+	// ResponseItemSchema describes each item of a sequential response, from itemSchema or x-stream-item.
+	ResponseItemSchema
+)
+
+// OperationSchema is a schema an operation declares directly.
+type OperationSchema struct {
+	Role SchemaRole
+	// Location is the document path of the schema below its operation, such as
+	// requestBody.content["application/json"].schema.
+	Location string
+	Schema   *Schema
+}
+
+// Schemas yields the schemas op declares in document order: parameters, request body content by media
+// type, then responses by status code with their headers before their content.
+func (op *Operation) Schemas() iter.Seq[OperationSchema] {
+	return func(yield func(OperationSchema) bool) {
+		emit := func(role SchemaRole, location string, s *Schema) bool {
+			return s == nil || yield(OperationSchema{Role: role, Location: location, Schema: s})
+		}
+		for i, param := range op.Parameters {
+			if param != nil && !emit(ParameterSchema, fmt.Sprintf("parameters[%d].schema", i), param.Schema) {
+				return
+			}
+		}
+		if op.RequestBody != nil {
+			for _, contentType := range SortedKeys(op.RequestBody.Content) {
+				if media := op.RequestBody.Content[contentType]; media != nil &&
+					!emit(RequestBodySchema, fmt.Sprintf("requestBody.content[%q].schema", contentType), media.Schema) {
+					return
+				}
+			}
+		}
+		for _, code := range SortedKeys(op.Responses) {
+			resp := op.Responses[code]
+			if resp == nil {
+				continue
+			}
+			for _, name := range SortedKeys(resp.Headers) {
+				if header := resp.Headers[name]; header != nil &&
+					!emit(ResponseHeaderSchema, fmt.Sprintf("responses[%q].headers[%q].schema", code, name), header.Schema) {
+					return
+				}
+			}
+			for _, contentType := range SortedKeys(resp.Content) {
+				media := resp.Content[contentType]
+				location := fmt.Sprintf("responses[%q].content[%q]", code, contentType)
+				if !emit(ResponseSchema, location+".schema", media.Schema) ||
+					!emit(ResponseItemSchema, location+".itemSchema", media.ItemSchema) ||
+					!emit(ResponseItemSchema, location+`["x-stream-item"]`, media.StreamItem) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SortedKeys returns the keys of m in ascending order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PascalCase turns an arbitrary key such as "line-item" into PascalCase, dropping every character
+// that is not a letter or digit.
+func PascalCase(name string) string {
+	var b strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}